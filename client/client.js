@@ -4,6 +4,9 @@ const readline = require("node:readline");
 
 const PROTO_PATH = "../proto/chat.proto";
 
+// Wire protocol version declared to the server in the first message
+const PROTOCOL_VERSION = 1;
+
 const packageDefinition = protoLoader.loadSync(PROTO_PATH, {
   keepCase: true,
   longs: String,
@@ -38,7 +41,17 @@ const rl = readline.createInterface({
   output: process.stdout,
 });
 
-const call = client.Connect();
+// Identify ourselves in the metadata too, for servers running with -identity-source=metadata
+const metadata = new grpc.Metadata();
+metadata.add("x-chat-user", user);
+metadata.add("x-chat-protocol-version", String(PROTOCOL_VERSION));
+
+// Bots and observers can opt out of join and leave announcements
+if (process.env.SKIP_SYSTEM_MESSAGES === "true") {
+  metadata.add("x-chat-skip-system-messages", "true");
+}
+
+const call = client.Connect(metadata);
 
 console.log(`Connected to chat as: ${user}`);
 
@@ -52,7 +65,11 @@ call.on("data", (message) => {
 
   // Only display messages from other users
   if (message.user !== user) {
-    console.log(`\n[${time}] ${message.user}: ${message.text}`);
+    const tags = message.tags.map((tag) => ` #${tag}`).join("");
+    console.log(`\n[${time}] ${message.user}: ${message.text}${tags}`);
+    if (message.attachment_url) {
+      console.log(`  Attachment: ${message.attachment_url}`);
+    }
   }
 });
 
@@ -68,11 +85,20 @@ call.on("error", (err) => {
   process.exit(1);
 });
 
-// Send the first message to register the user
-call.write({
-  user: user,
-  text: "Joined the room!",
-});
+// Send the first message to register the user, unless the metadata already did
+if (process.env.IDENTITY_SOURCE !== "metadata") {
+  call.write({
+    user: user,
+    text: "Joined the room!",
+    protocol_version: PROTOCOL_VERSION,
+  });
+}
+
+// Prove we are alive to servers running with -heartbeat-interval
+const HEARTBEAT_INTERVAL_MS = Number(process.env.HEARTBEAT_INTERVAL_MS || 0);
+if (HEARTBEAT_INTERVAL_MS > 0) {
+  setInterval(() => call.write({ type: "HEARTBEAT" }), HEARTBEAT_INTERVAL_MS);
+}
 
 // Read user input and send messages to the server
 rl.on("line", (line) => {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+// attachmentCheckTimeout bounds the HEAD request confirming an attachment exists.
+const attachmentCheckTimeout = 5 * time.Second
+
+// AttachmentValidator decides which attachment URLs messages may carry.
+// Attachments are only referenced, so the server never downloads their content.
+type AttachmentValidator struct {
+	hosts    map[string]bool // Hosts attachments may be served from (empty rejects every attachment)
+	schemes  map[string]bool // URL schemes accepted
+	check    bool            // Confirm with a HEAD request that the attachment exists
+	maxBytes int64           // Largest attachment accepted, as reported by the HEAD request (0 disables)
+	client   *http.Client    // Client issuing the HEAD requests
+}
+
+// NewAttachmentValidator creates a validator with the allowlists and limits from the configuration.
+func NewAttachmentValidator(config Config) *AttachmentValidator {
+	v := &AttachmentValidator{
+		hosts:    splitList(config.AttachmentHosts),
+		schemes:  splitList(config.AttachmentSchemes),
+		check:    config.AttachmentCheck,
+		maxBytes: config.AttachmentMaxBytes,
+	}
+	v.client = &http.Client{
+		Timeout: attachmentCheckTimeout,
+		// Redirects must not lead outside the allowlist either
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return v.checkURL(req.URL)
+		},
+	}
+	return v
+}
+
+// checkURL makes sure an attachment URL uses an accepted scheme and host.
+func (v *AttachmentValidator) checkURL(u *url.URL) error {
+	if !v.schemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("attachments must use %s", joinList(v.schemes))
+	}
+	if !v.hosts[strings.ToLower(u.Hostname())] {
+		return fmt.Errorf("attachments from %s are not allowed", u.Hostname())
+	}
+	return nil
+}
+
+// validate checks the attachment of a message, if any. With checking enabled it also
+// fetches the attachment headers and records its content type and size in the message.
+func (v *AttachmentValidator) validate(ctx context.Context, msg *pb.ChatMessage) error {
+	// Only the server describes attachments, whatever the client claims
+	msg.AttachmentContentType = ""
+	msg.AttachmentSize = 0
+	if msg.AttachmentUrl == "" {
+		return nil
+	}
+
+	u, err := url.Parse(msg.AttachmentUrl)
+	if err != nil || u.Host == "" {
+		return errors.New("the attachment is not a valid URL")
+	}
+	if err := v.checkURL(u); err != nil {
+		return err
+	}
+	if !v.check {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return errors.New("the attachment is not a valid URL")
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return errors.New("the attachment can't be reached")
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("the attachment can't be fetched (%s)", resp.Status)
+	}
+	// An unknown size can't be judged, so only a known one is held against the limit
+	if v.maxBytes > 0 && resp.ContentLength > v.maxBytes {
+		return fmt.Errorf("the attachment is %d bytes, the limit is %d", resp.ContentLength, v.maxBytes)
+	}
+
+	msg.AttachmentContentType = resp.Header.Get("Content-Type")
+	if resp.ContentLength > 0 {
+		msg.AttachmentSize = uint64(resp.ContentLength)
+	}
+	return nil
+}
+
+// splitList parses a comma-separated list into a set of lowercase values.
+func splitList(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
+
+// joinList formats a set for error messages.
+func joinList(set map[string]bool) string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	slices.Sort(values)
+	return strings.Join(values, " or ")
+}
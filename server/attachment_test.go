@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+func TestAttachmentAllowlists(t *testing.T) {
+	config := testConfig()
+	config.AttachmentHosts = "images.example.com, CDN.example.com"
+	config.AttachmentSchemes = "https"
+	v := NewAttachmentValidator(config)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"", ""},
+		{"https://images.example.com/cat.png", ""},
+		{"https://cdn.example.com/cat.png", ""},
+		{"http://images.example.com/cat.png", "attachments must use https"},
+		{"https://evil.example.com/cat.png", "attachments from evil.example.com are not allowed"},
+		{"cat.png", "the attachment is not a valid URL"},
+	}
+	for _, test := range tests {
+		msg := &pb.ChatMessage{AttachmentUrl: test.url, AttachmentContentType: "text/plain", AttachmentSize: 1}
+		err := v.validate(context.Background(), msg)
+		if got := errorText(err); got != test.want {
+			t.Errorf("validating %q: got %q, want %q", test.url, got, test.want)
+		}
+		// Only the server describes attachments
+		if msg.AttachmentContentType != "" || msg.AttachmentSize != 0 {
+			t.Errorf("validating %q kept the description of the client", test.url)
+		}
+	}
+}
+
+func TestAttachmentCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cat.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", "100")
+	})
+	mux.HandleFunc("/huge.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10000")
+	})
+	// Redirecting to a host outside the allowlist must not be followed
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		_, port, _ := net.SplitHostPort(r.Host)
+		http.Redirect(w, r, "http://localhost:"+port+"/cat.png", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := testConfig()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", server.URL, err)
+	}
+	config.AttachmentHosts = u.Hostname()
+	config.AttachmentSchemes = "http"
+	config.AttachmentCheck = true
+	config.AttachmentMaxBytes = 1000
+	v := NewAttachmentValidator(config)
+
+	msg := &pb.ChatMessage{AttachmentUrl: server.URL + "/cat.png"}
+	if err := v.validate(context.Background(), msg); err != nil {
+		t.Fatalf("validating an existing attachment: %v", err)
+	}
+	if msg.AttachmentContentType != "image/png" || msg.AttachmentSize != 100 {
+		t.Errorf("attachment described as %q of %d bytes, want image/png of 100", msg.AttachmentContentType, msg.AttachmentSize)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/missing.png", "the attachment can't be fetched (404 Not Found)"},
+		{"/huge.png", "the attachment is 10000 bytes, the limit is 1000"},
+		{"/elsewhere", "the attachment can't be reached"},
+	}
+	for _, test := range tests {
+		err := v.validate(context.Background(), &pb.ChatMessage{AttachmentUrl: server.URL + test.path})
+		if got := errorText(err); got != test.want {
+			t.Errorf("validating %s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestRejectAttachment(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	alice := ts.join(t, "alice")
+
+	if err := alice.Send(&pb.ChatMessage{Text: "look", AttachmentUrl: "https://example.com/cat.png"}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	expectText(t, alice, "Message not sent: attachments from example.com are not allowed.")
+}
+
+// errorText returns the text of err, or nothing if it is nil.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestThrottledAttachmentsAreNotFetched(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", server.URL, err)
+	}
+
+	clock := newFakeClock()
+	config := testConfig()
+	config.AttachmentHosts = u.Hostname()
+	config.AttachmentSchemes = "http"
+	config.AttachmentCheck = true
+	config.SlowMode = time.Minute
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+
+	for _, text := range []string{"first", "second"} {
+		if err := alice.Send(&pb.ChatMessage{Text: text, AttachmentUrl: server.URL + "/cat.png"}); err != nil {
+			t.Fatalf("sending: %v", err)
+		}
+	}
+	expectText(t, alice, "first")
+	expectText(t, alice, "Message not sent: slow mode is on, wait 60s before sending another message.")
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("the attachment was fetched %d times, want once", n)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// Clock tells the time and creates timers. The server reads time only through its
+// Clock, so time-dependent behavior can be driven by a fake clock instead of the real one.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time // Receives the time once the timer fires
+	Stop() bool          // Prevents the timer from firing, reporting whether it was still pending
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+// realTimer adapts a *time.Timer to the Timer interface.
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+
+func (t realTimer) Stop() bool { return t.timer.Stop() }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Coalescer folds identical messages sent to the room in quick succession, whoever sends them,
+// such as several bots echoing the same text. The first one is broadcast right away; the repeats
+// within the window are held back and delivered afterwards as a single message with a count.
+type Coalescer struct {
+	window time.Duration         // How long repeats of a message are folded (0 disables)
+	mutex  sync.Mutex            // Mutex to protect recent
+	recent map[string]*coalesced // Messages broadcast within the window, by text
+}
+
+// coalesced is a message whose repeats are being folded.
+type coalesced struct {
+	msg     *pb.ChatMessage // A copy of the first occurrence, as broadcast
+	since   time.Time       // When the first occurrence was received
+	repeats int             // Occurrences held back since then
+	senders []string        // Users who sent the repeats, in order of their first repeat
+}
+
+// NewCoalescer creates a coalescer with the window from the configuration.
+func NewCoalescer(config Config) *Coalescer {
+	return &Coalescer{
+		window: config.CoalesceWindow,
+		recent: make(map[string]*coalesced),
+	}
+}
+
+// enabled reports whether a window is configured.
+func (c *Coalescer) enabled() bool {
+	return c.window > 0
+}
+
+// absorb reports whether msg, received from user at now, repeats a message broadcast within the window, by anyone.
+// Absorbed messages must not be broadcast: they are accounted for in a later summary.
+func (c *Coalescer) absorb(user string, msg *pb.ChatMessage, now time.Time) bool {
+	if !c.enabled() {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, ok := c.recent[msg.Text]; ok && now.Sub(entry.since) < c.window {
+		entry.repeats++
+		if !slices.Contains(entry.senders, user) {
+			entry.senders = append(entry.senders, user)
+		}
+		return true
+	}
+	// Keep a copy, the sequencer numbers the message being broadcast
+	c.recent[msg.Text] = &coalesced{msg: proto.Clone(msg).(*pb.ChatMessage), since: now}
+	return false
+}
+
+// expire forgets the messages whose window is over at now, returning a summary for each one that had repeats.
+func (c *Coalescer) expire(now time.Time) []*pb.ChatMessage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var summaries []*pb.ChatMessage
+	for key, entry := range c.recent {
+		if now.Sub(entry.since) < c.window {
+			continue
+		}
+		delete(c.recent, key)
+		// The summary keeps everything else the message carried, like its tags and attachment
+		if entry.repeats > 0 {
+			summary := entry.msg
+			if len(entry.senders) == 1 && entry.senders[0] == summary.User {
+				summary.Text = fmt.Sprintf("%s (x%d)", summary.Text, entry.repeats)
+			} else {
+				// Repeats from other users can't be sent in the name of the first sender, the server reports them
+				summary.Text = fmt.Sprintf("%s (x%d from %s)", summary.Text, entry.repeats, listNames(entry.senders))
+				summary.User = systemUser
+				summary.Type = pb.MessageType_SYSTEM
+			}
+			summary.Timestamp = timestamppb.New(now)
+			summary.Seq = 0
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+// runCoalescer broadcasts the summaries of folded messages as their windows end, for as long as the server lives.
+func (s *ChatServer) runCoalescer() {
+	for {
+		timer := s.clock.NewTimer(s.coalescer.window)
+		<-timer.C()
+		for _, summary := range s.coalescer.expire(s.clock.Now()) {
+			s.publish(summary)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+func TestCoalesceRepeats(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.CoalesceWindow = 5 * time.Second
+	config.AttachmentHosts = "i.example.com"
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	const url = "https://i.example.com/cat.png"
+	for i := 0; i < 4; i++ {
+		if err := alice.Send(&pb.ChatMessage{Text: "look", AttachmentUrl: url}); err != nil {
+			t.Fatalf("sending: %v", err)
+		}
+	}
+	send(t, alice, "marker")
+	if msg := expectNext(t, bob); msg.User != "alice" || msg.Text != "look" {
+		t.Fatalf("bob received %v, want the first occurrence", msg)
+	}
+	if msg := expectNext(t, bob); msg.Text != "marker" {
+		t.Fatalf("bob received %v, want the marker", msg)
+	}
+
+	// Once the window is over, the repeats are delivered as one message
+	clock.waitTimers(t, 1)
+	clock.Advance(config.CoalesceWindow)
+	summary := expectNext(t, bob)
+	if summary.User != "alice" || summary.Text != "look (x3)" || summary.AttachmentUrl != url {
+		t.Fatalf("bob received %v, want the summary of the repeats of alice with their attachment", summary)
+	}
+	if !summary.Timestamp.AsTime().Equal(clock.Now()) {
+		t.Fatalf("summary sent at %v, want %v", summary.Timestamp.AsTime(), clock.Now())
+	}
+}
+
+func TestCoalesceAcrossSenders(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.CoalesceWindow = 5 * time.Second
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	carol := ts.join(t, "carol")
+	dave := ts.join(t, "dave")
+
+	// The same text from several bots is folded too, whoever sent it first
+	send(t, alice, "ping")
+	if msg := expectNext(t, dave); msg.User != "alice" || msg.Text != "ping" {
+		t.Fatalf("dave received %v, want the first occurrence", msg)
+	}
+	for i, sender := range []pb.ChatService_ConnectClient{bob, carol, bob} {
+		send(t, sender, "ping")
+		marker := fmt.Sprintf("marker %d", i)
+		send(t, sender, marker)
+		if msg := expectNext(t, dave); msg.Text != marker {
+			t.Fatalf("dave received %v, want %q", msg, marker)
+		}
+	}
+
+	// The summary comes from the server, since the first sender didn't send the repeats
+	clock.waitTimers(t, 1)
+	clock.Advance(config.CoalesceWindow)
+	summary := expectNext(t, dave)
+	if summary.User != systemUser || summary.Type != pb.MessageType_SYSTEM || summary.Text != "ping (x3 from bob, carol)" {
+		t.Fatalf("dave received %v, want the summary of the repeats of bob and carol", summary)
+	}
+}
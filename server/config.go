@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+)
+
+// protocolVersion is the wire protocol version spoken by this server.
+// Bump it whenever a change to chat.proto requires clients to be updated.
+const protocolVersion = 1
+
+// Config holds the server settings, read from the command-line flags.
+type Config struct {
+	MinProtocolVersion        uint32        // Oldest client protocol version accepted
+	MaxProtocolVersion        uint32        // Newest client protocol version accepted
+	TotalOrder                bool          // Serialize all broadcasts so every client sees the same order
+	SendQueueSize             int           // Messages buffered per connection
+	SendTimeout               time.Duration // How long a broadcast waits for room in a full send queue
+	SlowClientWindow          time.Duration // Window in which send timeouts count towards escalating
+	SlowClientReduceAfter     int           // Timeouts within the window before the client's queue is reduced (0 disables)
+	SlowClientDisconnectAfter int           // Timeouts within the window before the client is disconnected
+	ShutdownTimeout           time.Duration // How long a graceful shutdown may take before streams are cut
+	ShedMaxGoroutines         int           // Goroutine count above which new clients are refused (0 disables)
+	ShedMaxHeapMB             uint64        // Heap size above which new clients are refused (0 disables)
+	ShedMaxCPUPercent         float64       // CPU usage above which new clients are refused (0 disables)
+	MetricsAddr               string        // Address serving Prometheus metrics (empty disables)
+	LogMessageContent         bool          // Log the text of received messages, not only their metadata
+	MaxStatusQuery            int           // Users that can be queried in a single GetUserStatuses call
+	IdentitySource            string        // Where Connect reads the user from: "message" or "metadata"
+	MaxMessageLength          int           // Longest message accepted, in LengthUnit (0 disables)
+	LengthUnit                string        // How message length is counted: "byte", "rune" or "grapheme"
+	SendFailureThreshold      float64       // Share of failed sends that turns protective mode on (0 disables)
+	SendFailureWindow         time.Duration // Window over which the send failure rate is measured
+	SafeMode                  bool          // Disable every optional feature, whatever the other settings
+	MaxConcurrentHandshakes   int           // Connect handshakes allowed to run at once (0 disables)
+	HandshakeQueueTimeout     time.Duration // How long a client waits for a handshake slot before being rejected
+	DailyQuota                int           // Messages each user may send per day (0 disables)
+	QuotaResetAt              time.Duration // Time of day the daily quota resets, as an offset from midnight
+	QuotaTimezone             string        // IANA timezone the quota reset time is read in
+	SessionPolicy             string        // What a second session of a user does: "terminate-old" or "reject-new"
+	Normalize                 string        // How incoming text is normalized: "off", "light" or "strict"
+	CoalesceWindow            time.Duration // Window in which identical messages, from any user, are folded (0 disables)
+	SelfTest                  bool          // Check at startup that a client can chat through the server
+	FailOnSelfTest            bool          // Exit if the startup self-test fails
+	AttachmentHosts           string        // Comma-separated hosts attachments may link to (empty rejects attachments)
+	AttachmentSchemes         string        // Comma-separated URL schemes attachments may use
+	AttachmentCheck           bool          // Confirm with a HEAD request that attachments exist
+	AttachmentMaxBytes        int64         // Largest attachment accepted when checking (0 disables)
+	PresenceThreshold         int           // Active users above which joins and leaves aren't announced one by one (0 disables)
+	PresenceSnapshotInterval  time.Duration // How often folded joins and leaves are announced (0 never does)
+	HeartbeatInterval         time.Duration // How often clients must send a heartbeat (0 disables)
+	HeartbeatGrace            time.Duration // How late a heartbeat may be before the client is disconnected
+	TagRules                  string        // JSON file with the rules tagging messages, reloaded on SIGHUP (empty disables)
+	ControlChars              string        // What happens to messages with control characters: "off", "strip" or "reject"
+	SlowMode                  time.Duration // Minimum time between two messages of a user (0 disables)
+	UnknownTypePolicy         string        // What happens to messages of an unknown type: "drop", "passthrough" or "reject"
+	Pause                     bool          // Let clients pause and resume delivery with the Pause and Resume RPCs
+}
+
+// parseFlags reads the server configuration from the command line.
+func parseFlags() Config {
+	minVersion := flag.Uint("min-protocol-version", 0, "oldest client protocol version accepted (0 accepts clients that predate versioning)")
+	maxVersion := flag.Uint("max-protocol-version", protocolVersion, "newest client protocol version accepted")
+	totalOrder := flag.Bool("total-order", false, "serialize all messages through a sequencer so every client sees them in the same order")
+	sendQueueSize := flag.Int("send-queue-size", 64, "messages buffered per client")
+	sendTimeout := flag.Duration("send-timeout", 0, "how long a broadcast waits for room in a client's full send queue")
+	slowWindow := flag.Duration("slow-client-window", time.Minute, "window in which send timeouts count towards escalating")
+	slowReduceAfter := flag.Int("slow-client-reduce-after", 2, "send timeouts within the window before a client's queue is halved (0 disables)")
+	slowDisconnectAfter := flag.Int("slow-client-disconnect-after", 3, "send timeouts within the window before a client is disconnected")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "how long to wait for queued messages to be delivered when shutting down")
+	shedMaxGoroutines := flag.Int("shed-max-goroutines", 0, "refuse new clients above this many goroutines (0 disables)")
+	shedMaxHeapMB := flag.Uint64("shed-max-heap-mb", 0, "refuse new clients above this heap size in MB (0 disables)")
+	shedMaxCPU := flag.Float64("shed-max-cpu", 0, "refuse new clients above this CPU usage, in percent of GOMAXPROCS (0 disables)")
+	metricsAddr := flag.String("metrics-addr", "", "address serving Prometheus metrics on /metrics, e.g. :9090 (empty disables)")
+	logMessageContent := flag.Bool("log-message-content", false, "log the text of received messages instead of only their sender and length")
+	maxStatusQuery := flag.Int("max-status-query", 100, "users that can be queried in a single GetUserStatuses call")
+	identitySource := flag.String("identity-source", identityFromMessage, `where clients are identified from: "message" (their first message) or "metadata" (gRPC metadata)`)
+	maxMessageLength := flag.Int("max-message-length", 0, "longest message accepted, counted in -length-unit (0 disables)")
+	lengthUnit := flag.String("length-unit", lengthInRunes, `how message length is counted: "byte", "rune" or "grapheme" (user-perceived characters)`)
+	sendFailureThreshold := flag.Float64("send-failure-threshold", 0, "share of failed sends across all clients, from 0 to 1, above which new clients are refused (0 disables)")
+	sendFailureWindow := flag.Duration("send-failure-window", 10*time.Second, "window over which the send failure rate is measured")
+	maxConcurrentHandshakes := flag.Int("max-concurrent-handshakes", 0, "Connect handshakes allowed to run at once (0 disables)")
+	handshakeQueueTimeout := flag.Duration("handshake-queue-timeout", 0, "how long a client waits for a handshake slot before being rejected (0 rejects right away)")
+	dailyQuota := flag.Int("daily-quota", 0, "messages each user may send per day (0 disables)")
+	quotaReset := flag.String("quota-reset", "00:00", "time of day the daily quota resets, in the 15:04 format")
+	quotaTimezone := flag.String("quota-timezone", "Local", `timezone of -quota-reset, e.g. "UTC" or "America/Sao_Paulo"`)
+	sessionPolicy := flag.String("session-policy", sessionTerminateOld, `what happens when a connected user connects again: "terminate-old" (the old session is closed) or "reject-new"`)
+	normalize := flag.String("normalize", normalizeOff, `how incoming text is normalized: "off", "light" (NFKC and collapsed whitespace) or "strict" (light, and excessive combining marks stripped)`)
+	coalesceWindow := flag.Duration("coalesce-window", 0, "window in which identical messages, from any user, are folded into one delivery with a count (0 disables)")
+	selfTest := flag.Bool("selftest", false, "at startup, connect a client to the server and check that a message makes the round trip")
+	failOnSelfTest := flag.Bool("fail-on-selftest", false, "exit if the startup self-test fails, instead of only logging it")
+	attachmentHosts := flag.String("attachment-hosts", "", "comma-separated hosts messages may attach URLs from, e.g. i.imgur.com,cdn.example.com (empty rejects attachments)")
+	attachmentSchemes := flag.String("attachment-schemes", "https", "comma-separated URL schemes attachments may use")
+	attachmentCheck := flag.Bool("attachment-check", false, "confirm with a HEAD request that attachments exist, recording their content type and size")
+	attachmentMaxBytes := flag.Int64("attachment-max-bytes", 0, "largest attachment accepted, as reported by -attachment-check (0 disables)")
+	presenceThreshold := flag.Int("presence-threshold", 0, "active users above which joins and leaves are no longer announced one by one (0 disables)")
+	presenceSnapshotInterval := flag.Duration("presence-snapshot-interval", time.Minute, "how often the joins and leaves held back by -presence-threshold are announced together (0 never does, clients poll GetUserStatuses)")
+	heartbeatInterval := flag.Duration("heartbeat-interval", 0, "require clients to send a heartbeat message at this interval, disconnecting those that don't (0 disables)")
+	heartbeatGrace := flag.Duration("heartbeat-grace", 5*time.Second, "how late a heartbeat may be before the client is disconnected")
+	tagRules := flag.String("tag-rules", "", "JSON file with the rules tagging messages, reloaded on SIGHUP (empty disables)")
+	controlChars := flag.String("control-chars", controlCharsStrip, `what happens to messages with control characters other than newline and tab: "off" (nothing), "strip" (they are removed) or "reject"`)
+	slowMode := flag.Duration("slow-mode", 0, "minimum time between two messages of a user, e.g. 10s (0 disables)")
+	unknownTypePolicy := flag.String("unknown-type-policy", unknownTypeDrop, `what happens to messages of a type this server doesn't know: "drop", "passthrough" (broadcast like chat) or "reject" (the sender is told)`)
+	pause := flag.Bool("pause", true, "let clients pause and resume the delivery of their messages with the Pause and Resume RPCs")
+	safeMode := flag.Bool("safe-mode", false, "disable every optional feature and run the bare broadcast, regardless of the other flags")
+	flag.Parse()
+
+	config := Config{
+		MinProtocolVersion:        uint32(*minVersion),
+		MaxProtocolVersion:        uint32(*maxVersion),
+		TotalOrder:                *totalOrder,
+		SendQueueSize:             *sendQueueSize,
+		SendTimeout:               *sendTimeout,
+		SlowClientWindow:          *slowWindow,
+		SlowClientReduceAfter:     *slowReduceAfter,
+		SlowClientDisconnectAfter: *slowDisconnectAfter,
+		ShutdownTimeout:           *shutdownTimeout,
+		ShedMaxGoroutines:         *shedMaxGoroutines,
+		ShedMaxHeapMB:             *shedMaxHeapMB,
+		ShedMaxCPUPercent:         *shedMaxCPU,
+		MetricsAddr:               *metricsAddr,
+		LogMessageContent:         *logMessageContent,
+		MaxStatusQuery:            *maxStatusQuery,
+		IdentitySource:            *identitySource,
+		MaxMessageLength:          *maxMessageLength,
+		LengthUnit:                *lengthUnit,
+		SendFailureThreshold:      *sendFailureThreshold,
+		SendFailureWindow:         *sendFailureWindow,
+		SafeMode:                  *safeMode,
+		MaxConcurrentHandshakes:   *maxConcurrentHandshakes,
+		HandshakeQueueTimeout:     *handshakeQueueTimeout,
+		DailyQuota:                *dailyQuota,
+		QuotaTimezone:             *quotaTimezone,
+		SessionPolicy:             *sessionPolicy,
+		Normalize:                 *normalize,
+		CoalesceWindow:            *coalesceWindow,
+		SelfTest:                  *selfTest,
+		FailOnSelfTest:            *failOnSelfTest,
+		AttachmentHosts:           *attachmentHosts,
+		AttachmentSchemes:         *attachmentSchemes,
+		AttachmentCheck:           *attachmentCheck,
+		AttachmentMaxBytes:        *attachmentMaxBytes,
+		PresenceThreshold:         *presenceThreshold,
+		PresenceSnapshotInterval:  *presenceSnapshotInterval,
+		HeartbeatInterval:         *heartbeatInterval,
+		HeartbeatGrace:            *heartbeatGrace,
+		TagRules:                  *tagRules,
+		ControlChars:              *controlChars,
+		SlowMode:                  *slowMode,
+		UnknownTypePolicy:         *unknownTypePolicy,
+		Pause:                     *pause,
+	}
+	if config.MinProtocolVersion > config.MaxProtocolVersion {
+		log.Fatalf("Invalid configuration: -min-protocol-version (%d) is greater than -max-protocol-version (%d)", config.MinProtocolVersion, config.MaxProtocolVersion)
+	}
+	if config.SendQueueSize < 1 {
+		log.Fatalf("Invalid configuration: -send-queue-size must be at least 1")
+	}
+	if config.IdentitySource != identityFromMessage && config.IdentitySource != identityFromMetadata {
+		log.Fatalf("Invalid configuration: -identity-source must be %q or %q", identityFromMessage, identityFromMetadata)
+	}
+	switch config.LengthUnit {
+	case lengthInBytes, lengthInRunes, lengthInGraphemes:
+	default:
+		log.Fatalf("Invalid configuration: -length-unit must be %q, %q or %q", lengthInBytes, lengthInRunes, lengthInGraphemes)
+	}
+	if config.SendFailureThreshold > 0 && config.SendFailureWindow <= 0 {
+		log.Fatalf("Invalid configuration: -send-failure-window must be positive")
+	}
+	if config.SessionPolicy != sessionTerminateOld && config.SessionPolicy != sessionRejectNew {
+		log.Fatalf("Invalid configuration: -session-policy must be %q or %q", sessionTerminateOld, sessionRejectNew)
+	}
+	switch config.ControlChars {
+	case controlCharsOff, controlCharsStrip, controlCharsReject:
+	default:
+		log.Fatalf("Invalid configuration: -control-chars must be %q, %q or %q", controlCharsOff, controlCharsStrip, controlCharsReject)
+	}
+	switch config.UnknownTypePolicy {
+	case unknownTypeDrop, unknownTypePassthrough, unknownTypeReject:
+	default:
+		log.Fatalf("Invalid configuration: -unknown-type-policy must be %q, %q or %q", unknownTypeDrop, unknownTypePassthrough, unknownTypeReject)
+	}
+	switch config.Normalize {
+	case normalizeOff, normalizeLight, normalizeStrict:
+	default:
+		log.Fatalf("Invalid configuration: -normalize must be %q, %q or %q", normalizeOff, normalizeLight, normalizeStrict)
+	}
+	if config.AttachmentMaxBytes > 0 && !config.AttachmentCheck {
+		log.Fatalf("Invalid configuration: -attachment-max-bytes requires -attachment-check")
+	}
+	resetAt, err := parseTimeOfDay(*quotaReset)
+	if err != nil {
+		log.Fatalf("Invalid configuration: -quota-reset: %v", err)
+	}
+	config.QuotaResetAt = resetAt
+	if _, err := time.LoadLocation(config.QuotaTimezone); err != nil {
+		log.Fatalf("Invalid configuration: -quota-timezone: %v", err)
+	}
+	if config.SlowClientDisconnectAfter < 1 {
+		log.Fatalf("Invalid configuration: -slow-client-disconnect-after must be at least 1")
+	}
+	if config.SafeMode {
+		config.applySafeMode()
+		log.Println("Safe mode active: optional features are disabled.")
+	}
+	return config
+}
+
+// applySafeMode turns off every optional feature, leaving the bare broadcast behavior.
+// Settings clients depend on, like identity and protocol versions, are kept. So are the self-test
+// and the metrics endpoint: they only observe the server, which is what debugging in safe mode needs.
+// Add new optional features here so safe mode remains a known-good baseline.
+func (c *Config) applySafeMode() {
+	c.TotalOrder = false
+	c.SendTimeout = 0
+	c.SlowClientReduceAfter = 0
+	c.ShedMaxGoroutines = 0
+	c.ShedMaxHeapMB = 0
+	c.ShedMaxCPUPercent = 0
+	c.MaxMessageLength = 0
+	c.SendFailureThreshold = 0
+	c.MaxConcurrentHandshakes = 0
+	c.DailyQuota = 0
+	c.Normalize = normalizeOff
+	c.CoalesceWindow = 0
+	c.AttachmentHosts = ""
+	c.PresenceThreshold = 0
+	c.HeartbeatInterval = 0
+	c.TagRules = ""
+	c.SlowMode = 0
+	c.ControlChars = controlCharsOff
+	c.Pause = false
+}
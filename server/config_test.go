@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+func TestSafeMode(t *testing.T) {
+	config := testConfig()
+	config.TotalOrder = true
+	config.SendTimeout = time.Second
+	config.ShedMaxGoroutines = 1
+	config.ShedMaxHeapMB = 1
+	config.ShedMaxCPUPercent = 1
+	config.MaxMessageLength = 3
+	config.SendFailureThreshold = 0.5
+	config.MaxConcurrentHandshakes = 1
+	config.DailyQuota = 1
+	config.Normalize = normalizeStrict
+	config.CoalesceWindow = time.Minute
+	config.AttachmentHosts = "example.com"
+	config.PresenceThreshold = 1
+	config.HeartbeatInterval = time.Millisecond
+	config.TagRules = writeRules(t, `[{"pattern": ".", "tags": ["any"]}]`)
+	config.SlowMode = time.Minute
+	config.ControlChars = controlCharsReject
+	config.SelfTest = true
+	config.MetricsAddr = ":9090"
+	config.applySafeMode()
+	if !config.SelfTest || config.MetricsAddr != ":9090" {
+		t.Errorf("safe mode turned off the diagnostics: -selftest=%t, -metrics-addr=%q", config.SelfTest, config.MetricsAddr)
+	}
+
+	clock := newFakeClock()
+	ts := startServer(t, config, clock)
+	if ts.shedder.enabled() || ts.sendMonitor.enabled() || ts.handshakes != nil || ts.sequence != nil || ts.coalescer.enabled() || ts.presence.snapshots() {
+		t.Fatal("a background feature is running in safe mode")
+	}
+
+	// Past the presence threshold, joins are still announced
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+
+	// The text arrives as sent, as often as it is sent, without tags or sequence numbers
+	const text = "ｈｅｌｌｏ\x1b  there"
+	for range 3 {
+		send(t, alice, text)
+		msg := expectNext(t, bob)
+		if msg.Text != text || len(msg.Tags) != 0 || msg.Seq != 0 {
+			t.Fatalf("bob received %v, want the text as sent", msg)
+		}
+	}
+
+	// Attachments are off like every other optional feature
+	if err := alice.Send(&pb.ChatMessage{Text: "look", AttachmentUrl: "https://example.com/cat.png"}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	expectText(t, alice, "Message not sent: attachments from example.com are not allowed.")
+
+	// No background loop, like the heartbeat check, waits on the clock
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	if len(clock.timers) != 0 {
+		t.Fatalf("%d timers armed in safe mode", len(clock.timers))
+	}
+}
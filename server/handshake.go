@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errTooManyHandshakes rejects clients when every handshake slot stays busy.
+var errTooManyHandshakes = status.Error(codes.Unavailable, "too many clients connecting, please try again later")
+
+// acquireHandshake reserves one of the concurrent handshake slots, waiting up to
+// HandshakeQueueTimeout for one to free up, or until ctx is done. It returns a function
+// releasing the slot, which may be called more than once.
+func (s *ChatServer) acquireHandshake(ctx context.Context) (func(), error) {
+	if s.handshakes == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case s.handshakes <- struct{}{}:
+	default:
+		if s.config.HandshakeQueueTimeout <= 0 {
+			return nil, errTooManyHandshakes
+		}
+		timer := s.clock.NewTimer(s.config.HandshakeQueueTimeout)
+		defer timer.Stop()
+		select {
+		case s.handshakes <- struct{}{}:
+		case <-timer.C():
+			return nil, errTooManyHandshakes
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { <-s.handshakes }) }, nil
+}
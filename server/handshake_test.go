@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestHandshakeLimitRejectsSimultaneousConnects(t *testing.T) {
+	config := testConfig()
+	config.MaxConcurrentHandshakes = 2
+	ts := startServer(t, config, realClock{})
+
+	// Two handshakes are in progress
+	var releases []func()
+	for i := 0; i < config.MaxConcurrentHandshakes; i++ {
+		release, err := ts.acquireHandshake(context.Background())
+		if err != nil {
+			t.Fatalf("acquireHandshake: %v", err)
+		}
+		releases = append(releases, release)
+	}
+
+	// Every client connecting meanwhile is turned away
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		stream := ts.open(t, fmt.Sprintf("user%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expectCode(t, stream, codes.Unavailable)
+		}()
+	}
+	wg.Wait()
+
+	// Once a handshake is over, a slot is free again
+	releases[0]()
+	ts.join(t, "alice")
+	if len(ts.handshakes) != 1 {
+		t.Fatalf("%d handshake slots busy after alice joined, want 1", len(ts.handshakes))
+	}
+}
+
+func TestHandshakeQueue(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.MaxConcurrentHandshakes = 1
+	config.HandshakeQueueTimeout = time.Minute
+	ts := startServer(t, config, clock)
+
+	release, err := ts.acquireHandshake(context.Background())
+	if err != nil {
+		t.Fatalf("acquireHandshake: %v", err)
+	}
+
+	// A client waiting in the queue gets the slot once it frees up
+	alice := ts.open(t, "alice")
+	clock.waitTimers(t, 1)
+	release()
+	expectText(t, alice, "alice joined the room.")
+
+	// A client waiting longer than the queue timeout is rejected
+	release, err = ts.acquireHandshake(context.Background())
+	if err != nil {
+		t.Fatalf("acquireHandshake: %v", err)
+	}
+	defer release()
+	bob := ts.open(t, "bob")
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Minute)
+	expectCode(t, bob, codes.Unavailable)
+}
+
+func TestIdleStreamsDontHoldHandshakeSlots(t *testing.T) {
+	config := testConfig()
+	config.MaxConcurrentHandshakes = 1
+	ts := startServer(t, config, realClock{})
+
+	// Streams opened without ever identifying
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		if _, err := ts.client.Connect(ctx); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+	}
+	eventually(t, func() bool { return ts.streams.Load() == 3 })
+	time.Sleep(10 * time.Millisecond)
+
+	ts.join(t, "alice")
+}
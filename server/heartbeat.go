@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// heartbeatCheckInterval is how often the connections are checked for missed heartbeats.
+const heartbeatCheckInterval = time.Second
+
+// errMissedHeartbeat closes the connections that stopped sending heartbeats.
+var errMissedHeartbeat = status.Error(codes.DeadlineExceeded, "no heartbeat received in time")
+
+// heartbeat records that a client proved it is alive.
+func (c *Connection) heartbeat(now time.Time) {
+	c.lastBeat.Store(now.UnixNano())
+}
+
+// runHeartbeatCheck disconnects the clients that missed their heartbeat, for as long as the server lives.
+func (s *ChatServer) runHeartbeatCheck() {
+	for {
+		timer := s.clock.NewTimer(heartbeatCheckInterval)
+		<-timer.C()
+		s.checkHeartbeats(s.clock.Now())
+	}
+}
+
+// checkHeartbeats disconnects the clients whose last heartbeat, or their join if they never sent any,
+// is older than the heartbeat interval plus the grace period. Chat messages don't count: only heartbeats do.
+func (s *ChatServer) checkHeartbeats(now time.Time) {
+	deadline := now.Add(-s.config.HeartbeatInterval - s.config.HeartbeatGrace).UnixNano()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, connection := range s.connections {
+		if connection.lastBeat.Load() >= deadline {
+			continue
+		}
+		log.Printf("Client '%s' missed its heartbeat. Removing connection.", connection.user)
+		s.metrics.IncCounter(metricMissedHeartbeats)
+		// We use a goroutine to avoid deadlock (removeConnection uses Lock and we are called under RLock)
+		go s.removeConnection(connection, errMissedHeartbeat)
+	}
+}
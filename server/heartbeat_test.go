@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestMissedHeartbeatDisconnects(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.HeartbeatInterval = 2 * time.Second
+	config.HeartbeatGrace = time.Second
+	ts := startServer(t, config, clock)
+
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+
+	// Within the interval, nobody is late
+	clock.waitTimers(t, 1)
+	clock.Advance(2 * time.Second)
+
+	// Alice sends a heartbeat, Bob only chats
+	beat := clock.Now().UnixNano()
+	if err := alice.Send(&pb.ChatMessage{Type: pb.MessageType_HEARTBEAT}); err != nil {
+		t.Fatalf("sending heartbeat: %v", err)
+	}
+	send(t, bob, "still here")
+	expectText(t, alice, "still here")
+	aliceConnection := ts.connection(t, "alice")
+	eventually(t, func() bool { return aliceConnection.lastBeat.Load() == beat })
+
+	// Past the interval and the grace period since the join, Bob is disconnected
+	clock.waitTimers(t, 1)
+	clock.Advance(2 * time.Second)
+	expectCode(t, bob, codes.DeadlineExceeded)
+	expectText(t, alice, "bob left the room.")
+}
+
+func TestHeartbeatsKeepClientsAlive(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.HeartbeatInterval = 2 * time.Second
+	config.HeartbeatGrace = time.Second
+	ts := startServer(t, config, clock)
+
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+	aliceConnection, bobConnection := ts.connection(t, "alice"), ts.connection(t, "bob")
+
+	// Well past the interval, as long as the heartbeats keep coming
+	for range 5 {
+		beat := clock.Now().UnixNano()
+		for _, stream := range []pb.ChatService_ConnectClient{alice, bob} {
+			if err := stream.Send(&pb.ChatMessage{Type: pb.MessageType_HEARTBEAT}); err != nil {
+				t.Fatalf("sending heartbeat: %v", err)
+			}
+		}
+		eventually(t, func() bool {
+			return aliceConnection.lastBeat.Load() == beat && bobConnection.lastBeat.Load() == beat
+		})
+		clock.waitTimers(t, 1)
+		clock.Advance(2 * time.Second)
+	}
+
+	// Heartbeats aren't broadcast
+	send(t, alice, "still here")
+	for {
+		msg := expectNext(t, bob)
+		if msg.Type == pb.MessageType_HEARTBEAT {
+			t.Fatal("bob received a heartbeat")
+		}
+		if msg.Text == "still here" {
+			break
+		}
+	}
+}
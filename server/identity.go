@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Where Connect reads the identity of a client from.
+const (
+	identityFromMessage  = "message"  // The first message streamed by the client
+	identityFromMetadata = "metadata" // The gRPC metadata sent when opening the stream
+)
+
+// Metadata keys identifying the client when the identity comes from metadata.
+const (
+	userMetadataKey            = "x-chat-user"
+	protocolVersionMetadataKey = "x-chat-protocol-version"
+)
+
+// skipSystemMetadataKey lets a client, typically a bot, opt out of the system messages
+// broadcast to the room. Messages addressed to the client alone are still delivered.
+const skipSystemMetadataKey = "x-chat-skip-system-messages"
+
+// identify returns who is connecting and the protocol version they speak.
+// With metadata identification the client doesn't have to send anything before being connected.
+func (s *ChatServer) identify(stream pb.ChatService_ConnectServer) (user string, version uint32, err error) {
+	if s.config.IdentitySource == identityFromMetadata {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		user = firstValue(md, userMetadataKey)
+		if user == "" {
+			return "", 0, status.Errorf(codes.InvalidArgument, "missing %s metadata", userMetadataKey)
+		}
+		// Like in the first message, a missing version means a client that predates versioning
+		if value := firstValue(md, protocolVersionMetadataKey); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return "", 0, status.Errorf(codes.InvalidArgument, "invalid %s metadata: %q", protocolVersionMetadataKey, value)
+			}
+			version = uint32(parsed)
+		}
+		return user, version, nil
+	}
+
+	// Receive the first message to identify the user
+	initialMsg, err := stream.Recv()
+	if err != nil {
+		return "", 0, err
+	}
+	if initialMsg.User == "" {
+		return "", 0, status.Error(codes.InvalidArgument, "missing user in the first message")
+	}
+	return initialMsg.User, initialMsg.ProtocolVersion, nil
+}
+
+// skipsSystemMessages reads from the stream metadata whether the client opted out of system messages.
+func skipsSystemMessages(ctx context.Context) (bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	value := firstValue(md, skipSystemMetadataKey)
+	if value == "" {
+		return false, nil
+	}
+	skip, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid %s metadata: %q", skipSystemMetadataKey, value)
+	}
+	return skip, nil
+}
+
+// sessionTokenMetadataKey carries the token proving a unary RPC comes from the client holding a chat stream.
+// The server sends it in the header metadata of the Connect stream.
+const sessionTokenMetadataKey = "x-chat-session-token"
+
+// callerConnection finds the chat connection of the user named in the metadata of a unary RPC.
+func (s *ChatServer) callerConnection(ctx context.Context) (*Connection, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	user := firstValue(md, userMetadataKey)
+	if user == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing %s metadata", userMetadataKey)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	connection, ok := s.connections[user]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s is not connected", user)
+	}
+	return connection, nil
+}
+
+// callerSession finds the chat connection of the caller of a unary RPC like callerConnection,
+// and also requires the session token of that connection, so that nobody can act on behalf of another user.
+func (s *ChatServer) callerSession(ctx context.Context) (*Connection, error) {
+	connection, err := s.callerConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := firstValue(md, sessionTokenMetadataKey)
+	if token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing %s metadata", sessionTokenMetadataKey)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(connection.token)) != 1 {
+		return nil, status.Errorf(codes.PermissionDenied, "invalid %s metadata", sessionTokenMetadataKey)
+	}
+	return connection, nil
+}
+
+// firstValue returns the first value of a metadata key, or an empty string if it isn't set.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
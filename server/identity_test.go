@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestMetadataIdentity(t *testing.T) {
+	config := testConfig()
+	config.IdentitySource = identityFromMetadata
+	ts := startServer(t, config, realClock{})
+
+	// No handshake message is needed
+	alice := ts.join(t, "alice")
+	send(t, alice, "hello")
+	if msg := expectText(t, alice, "hello"); msg.User != "alice" {
+		t.Fatalf("message sent by %q, want alice", msg.User)
+	}
+
+	stream, err := ts.client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	expectCode(t, stream, codes.InvalidArgument)
+}
+
+func TestSenderCantBeImpersonated(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	if err := alice.Send(&pb.ChatMessage{User: "bob", Text: "I owe alice money"}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	if msg := expectText(t, bob, "I owe alice money"); msg.User != "alice" {
+		t.Fatalf("message sent by %q, want alice", msg.User)
+	}
+}
+
+func TestEmptyUserRejected(t *testing.T) {
+	for _, source := range []string{identityFromMessage, identityFromMetadata} {
+		config := testConfig()
+		config.IdentitySource = source
+		ts := startServer(t, config, realClock{})
+
+		// open identifies in both the metadata and the first message
+		err := expectCode(t, ts.open(t, ""), codes.InvalidArgument)
+		ts.mutex.RLock()
+		connected := len(ts.connections)
+		ts.mutex.RUnlock()
+		if connected != 0 {
+			t.Errorf("with %s identities, a client without a name was connected (%v)", source, err)
+		}
+	}
+}
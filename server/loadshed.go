@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// shedCheckInterval is how often the resource usage is sampled.
+const shedCheckInterval = time.Second
+
+// loadSample is a snapshot of the process resource usage.
+type loadSample struct {
+	goroutines int
+	heapBytes  uint64
+	cpu        float64 // Fraction of the available CPU used since the previous sample
+}
+
+// LoadShedder watches the process resource usage and tells when new work should be refused,
+// so that the clients already connected keep getting their messages under extreme load.
+type LoadShedder struct {
+	maxGoroutines int               // Goroutine count above which we shed (0 disables)
+	maxHeapBytes  uint64            // Heap size above which we shed (0 disables)
+	maxCPU        float64           // CPU fraction above which we shed (0 disables)
+	sample        func() loadSample // Reads the current usage, can be replaced to simulate pressure
+	shedding      atomic.Bool       // Whether we are currently shedding load
+	metrics       Metrics           // Receives the shedding state
+}
+
+// NewLoadShedder creates a shedder with the thresholds from the configuration.
+func NewLoadShedder(config Config, metrics Metrics) *LoadShedder {
+	return &LoadShedder{
+		maxGoroutines: config.ShedMaxGoroutines,
+		maxHeapBytes:  config.ShedMaxHeapMB << 20,
+		maxCPU:        config.ShedMaxCPUPercent / 100,
+		sample:        newLoadSampler(),
+		metrics:       metrics,
+	}
+}
+
+// enabled reports whether any threshold is configured.
+func (l *LoadShedder) enabled() bool {
+	return l.maxGoroutines > 0 || l.maxHeapBytes > 0 || l.maxCPU > 0
+}
+
+// Shedding reports whether new connections should currently be refused.
+func (l *LoadShedder) Shedding() bool {
+	return l.shedding.Load()
+}
+
+// run samples the resource usage at every interval of clock, for as long as the server lives.
+func (l *LoadShedder) run(clock Clock, interval time.Duration) {
+	for {
+		timer := clock.NewTimer(interval)
+		<-timer.C()
+		l.check(l.sample())
+	}
+}
+
+// check updates the shedding state from a sample, logging every transition.
+func (l *LoadShedder) check(sample loadSample) {
+	overloaded := (l.maxGoroutines > 0 && sample.goroutines > l.maxGoroutines) ||
+		(l.maxHeapBytes > 0 && sample.heapBytes > l.maxHeapBytes) ||
+		(l.maxCPU > 0 && sample.cpu > l.maxCPU)
+
+	if l.shedding.Swap(overloaded) != overloaded {
+		if overloaded {
+			l.metrics.SetGauge(metricLoadShedding, 1)
+			log.Printf("Load shedding engaged (goroutines: %d, heap: %d MB, CPU: %.0f%%).",
+				sample.goroutines, sample.heapBytes>>20, sample.cpu*100)
+		} else {
+			l.metrics.SetGauge(metricLoadShedding, 0)
+			log.Println("Load shedding disengaged.")
+		}
+	}
+}
+
+// newLoadSampler returns a function reading the current usage.
+// The CPU usage is measured between two consecutive calls.
+func newLoadSampler() func() loadSample {
+	lastCPU, lastWall := cpuTime(), time.Now()
+	return func() loadSample {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		now, used := time.Now(), cpuTime()
+		available := now.Sub(lastWall) * time.Duration(runtime.GOMAXPROCS(0))
+		sample := loadSample{
+			goroutines: runtime.NumGoroutine(),
+			heapBytes:  mem.HeapAlloc,
+		}
+		if available > 0 {
+			sample.cpu = float64(used-lastCPU) / float64(available)
+		}
+		lastCPU, lastWall = used, now
+		return sample
+	}
+}
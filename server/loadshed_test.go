@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestLoadShedding(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.ShedMaxGoroutines = 1000
+	ts := startServer(t, config, clock)
+
+	var goroutines atomic.Int64
+	goroutines.Store(10)
+	ts.shedder.sample = func() loadSample { return loadSample{goroutines: int(goroutines.Load())} }
+	alice := ts.join(t, "alice")
+
+	// The server runs out of headroom
+	goroutines.Store(5000)
+	clock.waitTimers(t, 1)
+	clock.Advance(shedCheckInterval)
+	eventually(t, ts.shedder.Shedding)
+
+	expectCode(t, ts.open(t, "bob"), codes.Unavailable)
+	stats, err := ts.client.GetRoomStats(context.Background(), &pb.RoomStatsRequest{})
+	if err != nil || !stats.Shedding {
+		t.Fatalf("GetRoomStats returned %v (%v), want shedding reported", stats, err)
+	}
+	// The clients already connected keep chatting
+	send(t, alice, "still here")
+	expectText(t, alice, "still here")
+
+	// Once the pressure is gone, clients are accepted again
+	goroutines.Store(10)
+	clock.waitTimers(t, 1)
+	clock.Advance(shedCheckInterval)
+	eventually(t, func() bool { return !ts.shedder.Shedding() })
+	ts.join(t, "bob")
+}
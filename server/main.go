@@ -1,24 +1,103 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	pb "github.com/artursilveiradev/grpc-chat/server/pb"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// systemUser is the sender shown on the messages published by the server itself, like join and leave
+// announcements. It is only a display name: system messages are told apart by their SYSTEM type.
+const systemUser = "Server"
+
 // Connection represents a single connected client.
 // We use a channel to send messages to this client.
 type Connection struct {
-	stream pb.ChatService_ConnectServer
-	user   string
-	error  chan error
+	stream     pb.ChatService_ConnectServer
+	user       string
+	id         uint64               // Unique among the connections since the server started
+	version    uint32               // Protocol version declared in the handshake
+	joinedAt   time.Time            // When the client joined the room
+	token      string               // Secret sent to the client, authenticating its unary RPCs on this connection
+	outbox     chan *pb.ChatMessage // Messages waiting to be sent by the writer (never closed)
+	queueLimit atomic.Int32         // How many messages may wait in outbox, lowered for slow clients
+	room       chan struct{}        // Signaled by the writer when it takes a message from outbox
+	done       chan struct{}        // Closed when the connection is torn down
+	closeOnce  sync.Once            // Makes sure done is closed only once
+	err        error                // Why the connection was closed, valid once done is closed
+	clock      Clock                // Clock used to time out enqueueing
+	skipSystem bool                 // Whether the client opted out of broadcast system messages
+	selfTest   bool                 // Whether this is the startup self-test, whose messages only go back to it
+	slowMutex  sync.Mutex           // Mutex to protect timeouts
+	timeouts   []time.Time          // Recent send timeouts, used to escalate on slow clients
+	lastBeat   atomic.Int64         // Unix nanoseconds of the last heartbeat, or of the join
+	paused     atomic.Bool          // Whether the client asked to stop receiving for now
+	resumed    chan struct{}        // Signaled when the client resumes receiving
+	missed     atomic.Uint64        // Messages dropped while paused with a full queue
+}
+
+// enqueue queues a message for the writer, waiting up to timeout for room in the queue.
+// It returns false if the message couldn't be queued in time. Messages for a closed connection are silently discarded.
+func (c *Connection) enqueue(msg *pb.ChatMessage, timeout time.Duration) bool {
+	var deadline <-chan time.Time
+	for {
+		select {
+		case <-c.done:
+			return true
+		default:
+		}
+
+		if len(c.outbox) < int(c.queueLimit.Load()) {
+			select {
+			case c.outbox <- msg:
+				return true
+			default:
+			}
+		}
+
+		// The queue is full, wait for the writer to make room
+		if timeout <= 0 {
+			return false
+		}
+		if deadline == nil {
+			timer := c.clock.NewTimer(timeout)
+			defer timer.Stop()
+			deadline = timer.C()
+		}
+		select {
+		case <-c.room:
+		case <-c.done:
+			return true
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// close tears the connection down, recording why. Only the first call has any effect.
+func (c *Connection) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.done)
+	})
 }
 
 // ChatServer stores all active connections.
@@ -26,74 +105,246 @@ type Connection struct {
 type ChatServer struct {
 	pb.UnimplementedChatServiceServer                        // Required for gRPC implementation
 	connections                       map[string]*Connection // Map of active connections (User -> Connection)
-	mutex                             sync.RWMutex           // Mutex to protect the map
+	lastSeen                          *LastSeen              // When each user who left was last connected
+	mutex                             sync.RWMutex           // Mutex to protect the maps
+	stats                             RoomStats              // Activity counters reported by GetRoomStats
+	config                            Config                 // Settings read from the command line
+	sequence                          chan *pb.ChatMessage   // Messages waiting for the sequencer (total-order mode only)
+	pending                           atomic.Int64           // Messages handed to the sequencer but not broadcast yet
+	shuttingDown                      bool                   // Set once Shutdown starts, protected by mutex
+	shedder                           *LoadShedder           // Refuses new clients when resources run low
+	sendMonitor                       *SendMonitor           // Refuses new clients when sends fail en masse
+	handshakes                        chan struct{}          // One slot per handshake in progress (nil when unlimited)
+	lastConnectionID                  atomic.Uint64          // Last id given to a connection
+	clock                             Clock                  // Source of time for timestamps and timeouts
+	quota                             *QuotaTracker          // Daily message counts per user
+	slowMode                          *SlowMode              // Spaces out the messages of each user
+	coalescer                         *Coalescer             // Folds messages repeated in quick succession
+	presence                          *PresenceThrottle      // Folds join and leave announcements in large rooms
+	attachments                       *AttachmentValidator   // Checks the attachment URLs messages carry
+	tagger                            *Tagger                // Categorizes messages with configured rules
+	seenTypes                         *UnknownTypes          // Unknown message types already logged
+	selfTestKey                       string                 // Secret letting the startup self-test use its reserved name
+	metrics                           Metrics                // Sink for the server instrumentation
+}
+
+// sequenceBuffer is how many messages can wait for the sequencer before publishers block.
+const sequenceBuffer = 256
+
+// NewChatServer creates a chat server with the given configuration, reporting to the given metrics sink
+// and reading time from clock. It fails if a file the configuration refers to can't be loaded.
+func NewChatServer(config Config, metrics Metrics, clock Clock) (*ChatServer, error) {
+	tagger, err := NewTagger(config)
+	if err != nil {
+		return nil, fmt.Errorf("loading tag rules: %w", err)
+	}
+
+	s := &ChatServer{
+		connections: make(map[string]*Connection),
+		lastSeen:    NewLastSeen(maxLastSeen),
+		config:      config,
+		shedder:     NewLoadShedder(config, metrics),
+		sendMonitor: NewSendMonitor(config, metrics),
+		quota:       NewQuotaTracker(config),
+		slowMode:    NewSlowMode(config),
+		coalescer:   NewCoalescer(config),
+		presence:    NewPresenceThrottle(config),
+		attachments: NewAttachmentValidator(config),
+		tagger:      tagger,
+		seenTypes:   NewUnknownTypes(),
+		selfTestKey: rand.Text(),
+		metrics:     metrics,
+		clock:       clock,
+	}
+	if s.shedder.enabled() {
+		go s.shedder.run(s.clock, shedCheckInterval)
+	}
+	if s.sendMonitor.enabled() {
+		go s.sendMonitor.run(s.clock, config.SendFailureWindow)
+	}
+	if config.MaxConcurrentHandshakes > 0 {
+		s.handshakes = make(chan struct{}, config.MaxConcurrentHandshakes)
+	}
+	if s.coalescer.enabled() {
+		go s.runCoalescer()
+	}
+	if s.presence.snapshots() {
+		go s.runPresenceSnapshots()
+	}
+	if config.HeartbeatInterval > 0 {
+		go s.runHeartbeatCheck()
+	}
+	if config.TagRules != "" {
+		go s.tagger.reloadOnHangup()
+	}
+	if config.TotalOrder {
+		s.sequence = make(chan *pb.ChatMessage, sequenceBuffer)
+		go s.runSequencer()
+	}
+	return s, nil
 }
 
 // Connect is the main method called when a client connects.
 func (s *ChatServer) Connect(stream pb.ChatService_ConnectServer) error {
 	log.Println("New client attempting to connect...")
 
-	// 1. Receive the first message to identify the user
-	initialMsg, err := stream.Recv()
+	// Under extreme load, keep resources for the clients already connected
+	if s.shedder.Shedding() {
+		log.Println("Rejected client: shedding load.")
+		s.metrics.IncCounter(metricConnectsRejected)
+		return status.Error(codes.Unavailable, "server is overloaded, please try again later")
+	}
+	if s.sendMonitor.Protective() {
+		log.Println("Rejected client: protective mode.")
+		s.metrics.IncCounter(metricConnectsRejected)
+		return status.Error(codes.Unavailable, "server is having trouble delivering messages, please try again later")
+	}
+
+	// 1. Identify the user, from the stream metadata or their first message
+	user, version, err := s.identify(stream)
 	if err != nil {
-		log.Printf("Error receiving initial message: %v", err)
+		log.Printf("Error identifying client: %v", err)
+		return err
+	}
+
+	// Limit how many handshakes run at once, so a connection storm doesn't spike resources.
+	// The slot is taken once the client identified: an idle stream mustn't hold one forever.
+	release, err := s.acquireHandshake(stream.Context())
+	if err != nil {
+		log.Printf("Rejected client '%s': %v", user, err)
+		s.metrics.IncCounter(metricConnectsRejected)
+		return err
+	}
+	defer release()
+
+	// 2. Reject clients speaking a protocol version we don't support
+	if err := s.checkProtocolVersion(version); err != nil {
+		log.Printf("Rejected client '%s': %v", user, err)
+		s.metrics.IncCounter(metricConnectsRejected)
 		return err
 	}
-	user := initialMsg.User
-	log.Printf("Client '%s' connected.", user)
 
-	// 2. Create the Connection struct for this client
+	// 3. Create the Connection struct for this client, with the preferences from its metadata
+	skipSystem, err := skipsSystemMessages(stream.Context())
+	if err != nil {
+		log.Printf("Rejected client '%s': %v", user, err)
+		s.metrics.IncCounter(metricConnectsRejected)
+		return err
+	}
+	selfTest, err := s.isSelfTest(stream.Context(), user)
+	if err != nil {
+		log.Printf("Rejected client '%s': %v", user, err)
+		s.metrics.IncCounter(metricConnectsRejected)
+		return err
+	}
+	log.Printf("Client '%s' connected.", user)
 	connection := &Connection{
-		stream: stream,
-		user:   user,
-		error:  make(chan error),
+		stream:     stream,
+		user:       user,
+		id:         s.lastConnectionID.Add(1),
+		version:    version,
+		joinedAt:   s.clock.Now(),
+		token:      rand.Text(),
+		outbox:     make(chan *pb.ChatMessage, s.config.SendQueueSize),
+		room:       make(chan struct{}, 1),
+		resumed:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		clock:      s.clock,
+		skipSystem: skipSystem,
+		selfTest:   selfTest,
+	}
+	connection.queueLimit.Store(int32(s.config.SendQueueSize))
+	connection.heartbeat(connection.joinedAt)
+	// The token goes out in the header metadata right away: a client skipping system messages
+	// may not receive anything for a long time
+	if err := stream.SendHeader(metadata.Pairs(sessionTokenMetadataKey, connection.token)); err != nil {
+		return err
 	}
 
-	// 3. Add the connection to the map (protected by Mutex)
-	s.addConnection(user, connection)
+	// 4. Add the connection to the map (protected by Mutex)
+	if err := s.addConnection(user, connection); err != nil {
+		log.Printf("Rejected client '%s': %v", user, err)
+		s.metrics.IncCounter(metricConnectsRejected)
+		return err
+	}
 
-	// 4. Announce to everyone that this user has joined
-	joinMsg := &pb.ChatMessage{
-		User:      "Server",
-		Text:      fmt.Sprintf("%s joined the room.", user),
-		Timestamp: timestamppb.Now(),
+	// 5. Announce to everyone that this user has joined, unless it is only the self-test
+	if !selfTest {
+		s.announcePresence(user, true)
 	}
-	s.broadcast(joinMsg)
+	release()
 
-	// 5. Start a goroutine to receive messages from this client
+	// 6. Start a goroutine to receive messages from this client
 	go s.receiveMessages(connection)
 
-	// 6. Return the error channel to know when the client disconnects
-	return <-connection.error
+	// 7. Send queued messages until the client disconnects
+	return s.sendMessages(connection)
+}
+
+// checkProtocolVersion makes sure the version declared in the handshake is within the supported range.
+// Clients that predate versioning don't set the field and are reported as version 0.
+func (s *ChatServer) checkProtocolVersion(version uint32) error {
+	minVersion, maxVersion := s.config.MinProtocolVersion, s.config.MaxProtocolVersion
+	if version < minVersion {
+		return status.Errorf(codes.FailedPrecondition,
+			"protocol version %d is no longer supported (server accepts %d to %d), please upgrade your client", version, minVersion, maxVersion)
+	}
+	if version > maxVersion {
+		return status.Errorf(codes.FailedPrecondition,
+			"protocol version %d is newer than this server supports (server accepts %d to %d)", version, minVersion, maxVersion)
+	}
+	return nil
 }
 
-// addConnection adds a client to the connections map
-func (s *ChatServer) addConnection(user string, connection *Connection) {
+// addConnection adds a client to the connections map, unless the server is shutting down
+func (s *ChatServer) addConnection(user string, connection *Connection) error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	if s.shuttingDown {
+		s.mutex.Unlock()
+		return errShutdown
+	}
+
+	// Apply the session policy if the user is already connected
+	old, exists := s.connections[user]
+	if exists && s.config.SessionPolicy == sessionRejectNew {
+		s.mutex.Unlock()
+		return errAlreadyConnected
+	}
 	s.connections[user] = connection
+	s.metrics.SetGauge(metricConnections, float64(len(s.connections)))
+	// Release the lock before notifying: the old session's queue may be full
+	s.mutex.Unlock()
+
+	if exists {
+		s.displace(old)
+	}
+	return nil
 }
 
-// removeConnection removes a client and announces their departure
-func (s *ChatServer) removeConnection(connection *Connection) {
+// removeConnection closes a client's connection, removes it and announces their departure
+func (s *ChatServer) removeConnection(connection *Connection, reason error) {
+	connection.close(reason)
+
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
 
-	// Check if the connection still exists (might have been removed by another goroutine)
-	if _, ok := s.connections[connection.user]; !ok {
+	// Check if the connection still exists (might have been removed by another goroutine,
+	// or replaced by a newer session of the same user)
+	if s.connections[connection.user] != connection {
+		s.mutex.Unlock()
 		return
 	}
 
 	delete(s.connections, connection.user)
+	s.lastSeen.record(connection.user, s.clock.Now())
+	s.metrics.SetGauge(metricConnections, float64(len(s.connections)))
+	// Release the lock before announcing: broadcast needs to take it again
+	s.mutex.Unlock()
 	log.Printf("Client '%s' disconnected.", connection.user)
 
 	// Announce to everyone that the user has left
-	leaveMsg := &pb.ChatMessage{
-		User:      "Server",
-		Text:      fmt.Sprintf("%s left the room.", connection.user),
-		Timestamp: timestamppb.Now(),
+	if !connection.selfTest {
+		s.announcePresence(connection.user, false)
 	}
-	s.broadcast(leaveMsg)
 }
 
 // receiveMessages runs in a separate goroutine for each client.
@@ -104,43 +355,248 @@ func (s *ChatServer) receiveMessages(connection *Connection) {
 
 		// If the client disconnects (io.EOF) or there's another error
 		if err == io.EOF {
-			s.removeConnection(connection)
-			connection.error <- nil // Inform the main goroutine that this client left
+			s.removeConnection(connection, nil) // The client left normally
 			return
 		}
 		if err != nil {
 			log.Printf("Error receiving from client %s: %v", connection.user, err)
-			s.removeConnection(connection)
-			connection.error <- err // Report the error
+			s.removeConnection(connection, err) // Report the error
+			return
+		}
+		// A closed connection, such as a session replaced by a newer one, no longer speaks for its user
+		select {
+		case <-connection.done:
+			s.removeConnection(connection, nil)
 			return
+		default:
 		}
 
+		// The sender is who the connection identified as, whatever the message claims,
+		// and only the sequencer numbers messages
+		msg.User = connection.user
+		msg.Seq = 0
+
+		// Heartbeats only prove the client is alive, they aren't broadcast
+		if msg.Type == pb.MessageType_HEARTBEAT {
+			connection.heartbeat(s.clock.Now())
+			continue
+		}
+		// Only the server sends system messages
+		if msg.Type == pb.MessageType_SYSTEM {
+			s.notify(connection, "Message not sent: only the server sends system messages.")
+			continue
+		}
+		// Newer clients may send types we don't know about
+		if !knownType(msg.Type) && !s.handleUnknownType(connection, msg) {
+			continue
+		}
+
+		// Clean the text up before judging it, normalization may shorten it
+		text, err := s.checkControlChars(msg.Text)
+		if err != nil {
+			s.notify(connection, fmt.Sprintf("Message not sent: %v.", err))
+			continue
+		}
+		msg.Text = normalizeText(text, s.config.Normalize)
+
+		// Reject messages that are too long, letting the sender know
+		if err := s.checkLength(msg.Text); err != nil {
+			s.notify(connection, fmt.Sprintf("Message not sent: %v.", err))
+			continue
+		}
+		if err := s.checkSlowMode(connection.user); err != nil {
+			s.notify(connection, fmt.Sprintf("Message not sent: %v.", err))
+			continue
+		}
+		if err := s.checkQuota(connection.user); err != nil {
+			s.notify(connection, fmt.Sprintf("Message not sent: %v.", err))
+			continue
+		}
+		// Last, since checking may fetch the attachment: throttled users can't make the server reach out
+		if err := s.attachments.validate(connection.stream.Context(), msg); err != nil {
+			s.notify(connection, fmt.Sprintf("Message not sent: %v.", err))
+			continue
+		}
+
+		// The message is accepted: it counts towards the quota, and the wait until the next one starts now
+		now := s.clock.Now()
+		s.slowMode.record(connection.user, now)
+		s.quota.count(connection.user, now)
+
+		// Only the server tags messages, whatever the client claims
+		msg.Tags = s.tagger.tag(msg.Text)
+
 		// Add a server timestamp
-		msg.Timestamp = timestamppb.Now()
+		msg.Timestamp = timestamppb.New(s.clock.Now())
+
+		// The probe of the self-test went through every check, it only makes the round trip to its own client
+		if connection.selfTest {
+			connection.enqueue(msg, s.config.SendTimeout)
+			continue
+		}
+		s.stats.record(msg.Timestamp.AsTime())
+		s.metrics.IncCounter(metricMessagesReceived)
 
-		// Broadcast the message to everyone else
+		// Broadcast the message to everyone else, unless it repeats one that was just broadcast
+		s.logMessage(msg)
+		if s.coalescer.absorb(connection.user, msg, msg.Timestamp.AsTime()) {
+			continue
+		}
+		s.publish(msg)
+	}
+}
+
+// notify sends a message from the server to a single client.
+func (s *ChatServer) notify(connection *Connection, text string) {
+	s.notifyWithin(connection, text, s.config.SendTimeout)
+}
+
+// notifyWithin is like notify, waiting up to timeout for room in the client's queue.
+func (s *ChatServer) notifyWithin(connection *Connection, text string, timeout time.Duration) {
+	notice := &pb.ChatMessage{
+		User:      systemUser,
+		Type:      pb.MessageType_SYSTEM,
+		Text:      text,
+		Timestamp: timestamppb.New(s.clock.Now()),
+	}
+	connection.enqueue(notice, timeout)
+}
+
+// logMessage logs a received message. Unless content logging is enabled,
+// only its metadata is logged so that what users write doesn't end up in the logs.
+func (s *ChatServer) logMessage(msg *pb.ChatMessage) {
+	if s.config.LogMessageContent {
 		log.Printf("Received from %s: %s", msg.User, msg.Text)
+		return
+	}
+	log.Printf("Received from %s (%d bytes)", msg.User, len(msg.Text))
+}
+
+// sendMessages is the writer of a connection: the only goroutine sending on its stream,
+// since gRPC doesn't allow concurrent sends. It returns once the connection is closed.
+func (s *ChatServer) sendMessages(connection *Connection) error {
+	for {
+		// While paused, leave the messages in the queue until the client resumes
+		if connection.paused.Load() {
+			select {
+			case <-connection.resumed:
+			case <-connection.done:
+				return s.finishMessages(connection)
+			}
+			continue
+		}
+
+		select {
+		case msg := <-connection.outbox:
+			// Wake up a broadcast waiting for room in the queue
+			select {
+			case connection.room <- struct{}{}:
+			default:
+			}
+
+			err := connection.stream.Send(msg)
+			s.sendMonitor.record(err)
+			if err != nil {
+				log.Printf("Error sending to %s: %v. Removing connection.", connection.user, err)
+				s.metrics.IncCounter(metricSendErrors)
+				s.removeConnection(connection, err)
+				return err
+			}
+			// A client that kept up for a whole window gets its full queue back
+			if connection.restoreQueue(s.clock.Now(), s.config) {
+				log.Printf("%s kept up with the room again. Restoring its queue to %d.", connection.user, s.config.SendQueueSize)
+			}
+		case <-connection.done:
+			return s.finishMessages(connection)
+		}
+	}
+}
+
+// finishMessages ends the writer of a closed connection, returning why it was closed.
+// On shutdown or when replaced, it delivers what was already queued before leaving.
+func (s *ChatServer) finishMessages(connection *Connection) error {
+	switch connection.err {
+	case errShutdown:
+		s.flushMessages(connection, s.config.ShutdownTimeout)
+	case errLoggedInElsewhere:
+		s.flushMessages(connection, displacedFlushTimeout)
+	}
+	return connection.err
+}
+
+// publish hands a message over to be broadcast.
+// In total-order mode it goes through the sequencer, otherwise it is broadcast right away.
+func (s *ChatServer) publish(msg *pb.ChatMessage) {
+	if s.sequence != nil {
+		s.pending.Add(1)
+		s.sequence <- msg
+		return
+	}
+	s.broadcast(msg)
+}
+
+// runSequencer broadcasts published messages one at a time, stamping each with the next Seq.
+// Since a single goroutine does all the broadcasting, every client sees the messages in the same order.
+func (s *ChatServer) runSequencer() {
+	var seq uint64
+	for msg := range s.sequence {
+		seq++
+		msg.Seq = seq
 		s.broadcast(msg)
+		s.pending.Add(-1)
+	}
+}
+
+// skipDead reports whether a connection is already known to be dead and should get no more messages.
+// A client whose stream is gone but wasn't removed yet is closed here and routed to removal.
+func (s *ChatServer) skipDead(connection *Connection) bool {
+	select {
+	case <-connection.done:
+		return true
+	default:
+	}
+
+	if err := connection.stream.Context().Err(); err != nil {
+		connection.close(status.FromContextError(err).Err())
+		// We use a goroutine to avoid deadlock (removeConnection uses Lock and we are called under RLock)
+		go s.removeConnection(connection, connection.err)
+		return true
 	}
+	return false
 }
 
 // broadcast sends a message to ALL connected clients
 func (s *ChatServer) broadcast(msg *pb.ChatMessage) {
+	// Latency is measured with the real clock, whatever s.clock says
+	start := time.Now()
 	s.mutex.RLock() // RLock allows for multiple concurrent reads
 	defer s.mutex.RUnlock()
+	defer func() { s.metrics.Observe(metricBroadcastSeconds, time.Since(start).Seconds()) }()
 
-	for user, connection := range s.connections {
-		// Send the message to the client's stream
-		if err := connection.stream.Send(msg); err != nil {
-			log.Printf("Error sending to %s: %v. Removing connection.", user, err)
-			// If sending fails, remove the connection
-			// We use a goroutine to avoid deadlock (removeConnection uses Lock)
-			go s.removeConnection(connection)
+	for _, connection := range s.connections {
+		if connection.skipSystem && msg.Type == pb.MessageType_SYSTEM {
+			continue
+		}
+		if s.skipDead(connection) {
+			continue
+		}
+		// A paused client isn't reading, so a full queue isn't a reason to escalate
+		if connection.paused.Load() {
+			if !connection.enqueue(msg, 0) {
+				connection.missed.Add(1)
+			}
+			continue
+		}
+		// Queue the message for the client's writer, escalating if it can't keep up
+		if !connection.enqueue(msg, s.config.SendTimeout) {
+			s.handleSendTimeout(connection)
 		}
 	}
 }
 
 func main() {
+	config := parseFlags()
+
 	port := ":50051"
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
@@ -151,16 +607,70 @@ func main() {
 	// Create the gRPC server
 	grpcServer := grpc.NewServer()
 
+	// Report metrics to Prometheus if an address is configured
+	var metrics Metrics = NopMetrics{}
+	if config.MetricsAddr != "" {
+		prometheusMetrics := NewPrometheusMetrics()
+		metrics = prometheusMetrics
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", prometheusMetrics.Handler())
+			log.Printf("Metrics available on http://%s/metrics", config.MetricsAddr)
+			if err := http.ListenAndServe(config.MetricsAddr, mux); err != nil {
+				log.Fatalf("Failed to serve metrics: %v", err)
+			}
+		}()
+	}
+
 	// Instantiate our chat server
-	chatServer := &ChatServer{
-		connections: make(map[string]*Connection),
+	chatServer, err := NewChatServer(config, metrics, realClock{})
+	if err != nil {
+		log.Fatalf("Failed to create the chat server: %v", err)
 	}
 
 	// Register the service with the gRPC server
 	pb.RegisterChatServiceServer(grpcServer, chatServer)
 
 	// Start the server
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	// Make sure a client can chat before relying on this instance
+	if config.SelfTest {
+		if err := selfTest("localhost"+port, config, chatServer.selfTestKey); err != nil {
+			if config.FailOnSelfTest {
+				log.Fatalf("Self-test failed: %v", err)
+			}
+			log.Printf("Self-test failed: %v", err)
+		} else {
+			log.Println("Self-test passed.")
+		}
+	}
+
+	// Wait for a termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	// Deliver the messages already accepted, then stop once every stream has ended
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	chatServer.Shutdown(shutdownCtx)
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Println("Server stopped.")
+	case <-shutdownCtx.Done():
+		log.Println("Shutdown timed out, closing the remaining streams.")
+		grpcServer.Stop()
 	}
 }
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testTimeout bounds how long a test waits for the server before failing.
+const testTimeout = 5 * time.Second
+
+// TestMain keeps the server logs out of the test output, unless running verbosely.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// testConfig returns the configuration parseFlags produces when no flag is given.
+func testConfig() Config {
+	return Config{
+		MaxProtocolVersion:        protocolVersion,
+		SendQueueSize:             64,
+		SlowClientWindow:          time.Minute,
+		SlowClientReduceAfter:     2,
+		SlowClientDisconnectAfter: 3,
+		ShutdownTimeout:           10 * time.Second,
+		MaxStatusQuery:            100,
+		IdentitySource:            identityFromMessage,
+		LengthUnit:                lengthInRunes,
+		SendFailureWindow:         10 * time.Second,
+		QuotaTimezone:             "Local",
+		SessionPolicy:             sessionTerminateOld,
+		Normalize:                 normalizeOff,
+		AttachmentSchemes:         "https",
+		PresenceSnapshotInterval:  time.Minute,
+		HeartbeatGrace:            5 * time.Second,
+		ControlChars:              controlCharsStrip,
+		UnknownTypePolicy:         unknownTypeDrop,
+		Pause:                     true,
+	}
+}
+
+// testServer is a chat server served over an in-memory connection.
+type testServer struct {
+	*ChatServer
+	client  pb.ChatServiceClient
+	streams atomic.Int32 // Connect calls the server started handling
+}
+
+// startServer serves a chat server with the given configuration and clock until the test ends.
+func startServer(t testing.TB, config Config, clock Clock) *testServer {
+	t.Helper()
+	chatServer, err := NewChatServer(config, NopMetrics{}, clock)
+	if err != nil {
+		t.Fatalf("NewChatServer: %v", err)
+	}
+	return serve(t, chatServer)
+}
+
+// serve serves chatServer over an in-memory connection until the test ends.
+func serve(t testing.TB, chatServer *ChatServer) *testServer {
+	t.Helper()
+
+	ts := &testServer{ChatServer: chatServer}
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(grpc.StreamInterceptor(
+		func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ts.streams.Add(1)
+			return handler(srv, stream)
+		}))
+	pb.RegisterChatServiceServer(grpcServer, chatServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ts.client = pb.NewChatServiceClient(conn)
+	return ts
+}
+
+// open opens a chat stream as user, identifying in the metadata and, unless the server
+// reads identities from metadata, in the first message. Extra metadata pairs can be given.
+func (ts *testServer) open(t testing.TB, user string, pairs ...string) pb.ChatService_ConnectClient {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	t.Cleanup(cancel)
+	return ts.openContext(t, ctx, user, pairs...)
+}
+
+// openContext is like open, with the stream living as long as ctx.
+func (ts *testServer) openContext(t testing.TB, ctx context.Context, user string, pairs ...string) pb.ChatService_ConnectClient {
+	t.Helper()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, append([]string{
+		userMetadataKey, user,
+		protocolVersionMetadataKey, strconv.Itoa(protocolVersion),
+	}, pairs...)...)
+	stream, err := ts.client.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect as %s: %v", user, err)
+	}
+	if ts.config.IdentitySource == identityFromMessage {
+		if err := stream.Send(&pb.ChatMessage{User: user, ProtocolVersion: protocolVersion}); err != nil {
+			t.Fatalf("sending the handshake of %s: %v", user, err)
+		}
+	}
+	return stream
+}
+
+// join connects user and waits until they are in the room.
+func (ts *testServer) join(t testing.TB, user string, pairs ...string) pb.ChatService_ConnectClient {
+	t.Helper()
+	stream := ts.open(t, user, pairs...)
+	expectText(t, stream, user+" joined the room.")
+	return stream
+}
+
+// connection returns the connection of user, waiting for it to be added.
+func (ts *testServer) connection(t testing.TB, user string) *Connection {
+	t.Helper()
+	var connection *Connection
+	eventually(t, func() bool {
+		ts.mutex.RLock()
+		defer ts.mutex.RUnlock()
+		connection = ts.connections[user]
+		return connection != nil
+	})
+	return connection
+}
+
+// send sends a chat message on stream.
+func send(t testing.TB, stream pb.ChatService_ConnectClient, text string) {
+	t.Helper()
+	if err := stream.Send(&pb.ChatMessage{Text: text}); err != nil {
+		t.Fatalf("sending %q: %v", text, err)
+	}
+}
+
+// expectText receives from stream until a message with the given text, which it returns.
+func expectText(t testing.TB, stream pb.ChatService_ConnectClient, text string) *pb.ChatMessage {
+	t.Helper()
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", text, err)
+		}
+		if msg.Text == text {
+			return msg
+		}
+	}
+}
+
+// expectNext receives the next message from stream.
+func expectNext(t testing.TB, stream pb.ChatService_ConnectClient) *pb.ChatMessage {
+	t.Helper()
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receiving: %v", err)
+	}
+	return msg
+}
+
+// expectCode receives from stream until it ends, checking it ends with the given code.
+func expectCode(t testing.TB, stream pb.ChatService_ConnectClient, code codes.Code) error {
+	t.Helper()
+	for {
+		_, err := stream.Recv()
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != code {
+			t.Fatalf("stream ended with %v, want %v", err, code)
+		}
+		return err
+	}
+}
+
+// eventually fails the test unless condition becomes true before testTimeout.
+func eventually(t testing.TB, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fakeClock is a Clock whose time only moves when the test advances it.
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a Timer created by a fakeClock.
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- c.now
+		return timer
+	}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the time forward, firing the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.now
+	}
+	c.timers = pending
+}
+
+// waitTimers waits until n timers are pending, so that a background loop
+// has armed its next timer before the time is advanced.
+func (c *fakeClock) waitTimers(t testing.TB, n int) {
+	t.Helper()
+	eventually(t, func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(c.timers) >= n
+	})
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func TestSkipSystemMessages(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+
+	bot := ts.open(t, "bot", skipSystemMetadataKey, "true")
+	ts.connection(t, "bot")
+	alice := ts.join(t, "alice")
+
+	// Posing as the server doesn't make a message a system one
+	if err := alice.Send(&pb.ChatMessage{User: systemUser, Text: "hi"}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	if msg, err := bot.Recv(); err != nil || msg.Text != "hi" || msg.Type != pb.MessageType_CHAT {
+		t.Fatalf("bot received %v (%v), want the chat message of alice", msg, err)
+	}
+
+	// Clients can't send system messages
+	if err := alice.Send(&pb.ChatMessage{Type: pb.MessageType_SYSTEM, Text: "Server is shutting down."}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	notice := expectText(t, alice, "Message not sent: only the server sends system messages.")
+	if notice.Type != pb.MessageType_SYSTEM {
+		t.Fatalf("notice has type %v, want SYSTEM", notice.Type)
+	}
+	send(t, alice, "after")
+	if msg, err := bot.Recv(); err != nil || msg.Text != "after" {
+		t.Fatalf("bot received %v (%v), want the next chat message of alice", msg, err)
+	}
+}
+
+func TestTotalOrder(t *testing.T) {
+	config := testConfig()
+	config.TotalOrder = true
+	config.SendTimeout = time.Second // Bursts may fill the queues for a moment
+	ts := startServer(t, config, realClock{})
+
+	const senders, messages = 4, 50
+	streams := make([]pb.ChatService_ConnectClient, senders)
+	for i := range streams {
+		streams[i] = ts.join(t, fmt.Sprintf("user%d", i))
+	}
+	// Drain the join announcements of the users who came later
+	for _, stream := range streams[:senders-1] {
+		expectText(t, stream, fmt.Sprintf("user%d joined the room.", senders-1))
+	}
+
+	// Everyone reads while everyone talks at once, with made-up sequence numbers
+	orders := make([][]string, senders)
+	var readers, writers sync.WaitGroup
+	for i, stream := range streams {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			var lastSeq uint64
+			for len(orders[i]) < senders*messages {
+				msg, err := stream.Recv()
+				if err != nil {
+					t.Errorf("receiving: %v", err)
+					return
+				}
+				if msg.Seq <= lastSeq {
+					t.Errorf("seq %d after %d", msg.Seq, lastSeq)
+					return
+				}
+				lastSeq = msg.Seq
+				orders[i] = append(orders[i], msg.Text)
+			}
+		}()
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < messages; j++ {
+				if err := stream.Send(&pb.ChatMessage{Text: fmt.Sprintf("%d-%d", i, j), Seq: 1}); err != nil {
+					t.Errorf("sending: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	readers.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// Every client saw the same messages in the same order, numbered by the sequencer
+	for i := range orders {
+		if !slices.Equal(orders[i], orders[0]) {
+			t.Fatalf("user%d saw the messages in another order than user0", i)
+		}
+	}
+}
+
+func TestSeqOnlySetBySequencer(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	alice := ts.join(t, "alice")
+
+	if err := alice.Send(&pb.ChatMessage{Text: "first", Seq: 1000}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	if msg := expectText(t, alice, "first"); msg.Seq != 0 {
+		t.Fatalf("message has seq %d outside total-order mode, want 0", msg.Seq)
+	}
+}
+
+// fakeStream is a server side Connect stream driven by the test, to stage what a real client can't.
+type fakeStream struct {
+	pb.ChatService_ConnectServer
+	ctx  context.Context
+	recv chan *pb.ChatMessage // Messages from the client; closing it ends the stream
+	sent chan *pb.ChatMessage // Messages to the client; Send blocks while it is full
+}
+
+func newFakeStream(t testing.TB, sendBuffer int) *fakeStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &fakeStream{ctx: ctx, recv: make(chan *pb.ChatMessage, 16), sent: make(chan *pb.ChatMessage, sendBuffer)}
+}
+
+func (f *fakeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeStream) Recv() (*pb.ChatMessage, error) {
+	msg, ok := <-f.recv
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (f *fakeStream) Send(msg *pb.ChatMessage) error {
+	select {
+	case f.sent <- msg:
+		return nil
+	case <-f.ctx.Done():
+		return f.ctx.Err()
+	}
+}
+
+func TestBroadcastWhileClientsDisconnect(t *testing.T) {
+	config := testConfig()
+	config.SendQueueSize = 8
+	config.SendTimeout = time.Millisecond
+	ts := startServer(t, config, realClock{})
+
+	const talkers, churners, rounds, messages = 4, 16, 10, 200
+	var wg sync.WaitGroup
+
+	// Talkers broadcast continuously, draining what they receive. They may fall behind
+	// and be disconnected like any slow client, which is fine: we look for panics and races
+	for i := 0; i < talkers; i++ {
+		stream := ts.join(t, fmt.Sprintf("talker%d", i))
+		go func() {
+			for {
+				if _, err := stream.Recv(); err != nil {
+					return
+				}
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				if err := stream.Send(&pb.ChatMessage{Text: fmt.Sprintf("message %d", j)}); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	// Meanwhile other clients come and go: leaving cleanly, or dropping the stream right away or a moment later
+	for i := 0; i < churners; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user := fmt.Sprintf("churner%d", i)
+			for j := 0; j < rounds; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+				stream := ts.openContext(t, ctx, user)
+				stream.Recv()
+				switch j % 3 {
+				case 0:
+					stream.CloseSend()
+					for {
+						if _, err := stream.Recv(); err != nil {
+							break
+						}
+					}
+				case 1:
+					time.Sleep(time.Millisecond)
+				}
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The room is still in a working state
+	alice := ts.join(t, "alice")
+	send(t, alice, "anyone left?")
+	expectText(t, alice, "anyone left?")
+}
+
+func TestSenderEchoIsCanonical(t *testing.T) {
+	config := testConfig()
+	config.TotalOrder = true
+	config.Normalize = normalizeLight
+	config.TagRules = writeRules(t, `[{"pattern": "\\?$", "tags": ["question"]}]`)
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	// The sender's copy is the one the room gets, after every transformation of the server
+	send(t, alice, "ｈｉ\x00  there?")
+	msg := expectText(t, alice, "hi there?")
+	if msg.User != "alice" || msg.Seq == 0 || msg.Timestamp == nil || !slices.Equal(msg.Tags, []string{"question"}) {
+		t.Fatalf("alice received %v, want the canonical copy of the message", msg)
+	}
+}
+
+func TestBroadcastSkipsDeadConnections(t *testing.T) {
+	s, err := NewChatServer(testConfig(), NopMetrics{}, realClock{})
+	if err != nil {
+		t.Fatalf("NewChatServer: %v", err)
+	}
+	addConnection := func(user string, stream *fakeStream) *Connection {
+		connection := &Connection{
+			stream: stream,
+			user:   user,
+			outbox: make(chan *pb.ChatMessage, 8),
+			room:   make(chan struct{}, 1),
+			done:   make(chan struct{}),
+			clock:  s.clock,
+		}
+		connection.queueLimit.Store(8)
+		s.connections[user] = connection
+		return connection
+	}
+	alive := addConnection("alive", newFakeStream(t, 0))
+	// The client of this one went away, but its connection wasn't removed yet
+	stream := newFakeStream(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream.ctx = ctx
+	dead := addConnection("dead", stream)
+
+	s.broadcast(&pb.ChatMessage{User: "alive", Text: "hello"})
+	if len(dead.outbox) != 0 {
+		t.Fatal("a message was queued for the dead connection")
+	}
+	if msg := <-alive.outbox; msg.Text != "hello" {
+		t.Fatalf("the live connection got %q, want the message", msg.Text)
+	}
+	eventually(t, func() bool {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+		_, ok := s.connections["dead"]
+		return !ok
+	})
+	if status.Code(dead.err) != codes.Canceled {
+		t.Fatalf("the dead connection was closed with %v, want Canceled", dead.err)
+	}
+}
+
+func TestLogMessage(t *testing.T) {
+	const text = "my password is hunter2"
+	var logs strings.Builder
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+
+	for _, logContent := range []bool{false, true} {
+		logs.Reset()
+		s := &ChatServer{config: Config{LogMessageContent: logContent}}
+		s.logMessage(&pb.ChatMessage{User: "alice", Text: text})
+
+		line := logs.String()
+		if !strings.Contains(line, "alice") {
+			t.Errorf("log line %q doesn't name the sender", line)
+		}
+		if logContent != strings.Contains(line, text) {
+			t.Errorf("with -log-message-content=%t, got log line %q", logContent, line)
+		}
+		if !logContent && !strings.Contains(line, fmt.Sprintf("(%d bytes)", len(text))) {
+			t.Errorf("log line %q doesn't give the length", line)
+		}
+	}
+}
+
+// BenchmarkBroadcast measures the fan-out of a message to N connections whose writers keep up,
+// and how a single reader that stopped reading slows it down.
+func BenchmarkBroadcast(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("readers=%d", n), func(b *testing.B) {
+			benchmarkBroadcast(b, n, false)
+		})
+		b.Run(fmt.Sprintf("readers=%d/stalled", n), func(b *testing.B) {
+			benchmarkBroadcast(b, n, true)
+		})
+	}
+}
+
+func benchmarkBroadcast(b *testing.B, readers int, stalled bool) {
+	config := testConfig()
+	config.SendTimeout = time.Millisecond
+	config.SlowClientDisconnectAfter = math.MaxInt // Keep the stalled reader around
+	s, err := NewChatServer(config, NopMetrics{}, realClock{})
+	if err != nil {
+		b.Fatalf("NewChatServer: %v", err)
+	}
+	addConnection := func(user string) *Connection {
+		connection := &Connection{
+			stream: newFakeStream(b, 0),
+			user:   user,
+			outbox: make(chan *pb.ChatMessage, config.SendQueueSize),
+			room:   make(chan struct{}, 1),
+			done:   make(chan struct{}),
+			clock:  s.clock,
+		}
+		connection.queueLimit.Store(int32(config.SendQueueSize))
+		s.connections[user] = connection
+		b.Cleanup(func() { connection.close(nil) })
+		return connection
+	}
+	for i := 0; i < readers; i++ {
+		// Stand in for the writer, taking messages as fast as they come
+		connection := addConnection(fmt.Sprintf("reader%d", i))
+		go func() {
+			for {
+				select {
+				case <-connection.outbox:
+					select {
+					case connection.room <- struct{}{}:
+					default:
+					}
+				case <-connection.done:
+					return
+				}
+			}
+		}()
+	}
+	if stalled {
+		addConnection("stalled")
+	}
+
+	msg := &pb.ChatMessage{User: "bench", Text: "hello, room"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.broadcast(msg)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*readers), "ns/delivery")
+}
+
+func TestProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		minVersion uint32
+		version    uint32
+		code       codes.Code
+	}{
+		{"client predating versioning", 0, 0, codes.OK},
+		{"current client", 0, protocolVersion, codes.OK},
+		{"client too old", protocolVersion, 0, codes.FailedPrecondition},
+		{"client too new", 0, protocolVersion + 1, codes.FailedPrecondition},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := testConfig()
+			config.MinProtocolVersion = test.minVersion
+			ts := startServer(t, config, realClock{})
+
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+			stream, err := ts.client.Connect(ctx)
+			if err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+			if err := stream.Send(&pb.ChatMessage{User: "alice", ProtocolVersion: test.version}); err != nil {
+				t.Fatalf("sending the handshake: %v", err)
+			}
+			if test.code == codes.OK {
+				expectText(t, stream, "alice joined the room.")
+				return
+			}
+			err = expectCode(t, stream, test.code)
+			if !strings.Contains(status.Convert(err).Message(), fmt.Sprintf("server accepts %d to %d", test.minVersion, protocolVersion)) {
+				t.Fatalf("error %q doesn't tell the accepted versions", err)
+			}
+		})
+	}
+}
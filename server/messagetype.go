@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+// What happens to messages of a type this server doesn't know, sent by newer clients.
+const (
+	unknownTypeDrop        = "drop"        // The message is silently discarded
+	unknownTypePassthrough = "passthrough" // The message is broadcast like a chat message, keeping its type
+	unknownTypeReject      = "reject"      // The message is discarded and the sender is told why
+)
+
+// maxLoggedTypes caps how many unknown message types are remembered as logged.
+// Clients choose the values, so the set must not grow without bounds.
+const maxLoggedTypes = 64
+
+// UnknownTypes remembers the unknown message types already logged, so each is only logged once.
+type UnknownTypes struct {
+	mutex  sync.Mutex                  // Mutex to protect logged
+	logged map[pb.MessageType]struct{} // Types logged so far, at most maxLoggedTypes
+}
+
+// NewUnknownTypes creates an empty set of logged types.
+func NewUnknownTypes() *UnknownTypes {
+	return &UnknownTypes{logged: make(map[pb.MessageType]struct{})}
+}
+
+// firstSeen reports whether a type should be logged: the first time it is seen, while the set isn't full.
+// Once maxLoggedTypes types were logged, new ones are only counted.
+func (u *UnknownTypes) firstSeen(t pb.MessageType) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if _, ok := u.logged[t]; ok || len(u.logged) >= maxLoggedTypes {
+		return false
+	}
+	u.logged[t] = struct{}{}
+	return true
+}
+
+// knownType reports whether this server knows how to handle a message type.
+func knownType(t pb.MessageType) bool {
+	_, ok := pb.MessageType_name[int32(t)]
+	return ok
+}
+
+// handleUnknownType applies the unknown type policy to a message, reporting whether it should still be broadcast.
+func (s *ChatServer) handleUnknownType(connection *Connection, msg *pb.ChatMessage) bool {
+	s.metrics.IncCounter(metricUnknownTypes)
+	if s.seenTypes.firstSeen(msg.Type) {
+		log.Printf("Received message type %d from %s, which this server doesn't know. Applying the %q policy.", msg.Type, connection.user, s.config.UnknownTypePolicy)
+	}
+
+	switch s.config.UnknownTypePolicy {
+	case unknownTypePassthrough:
+		return true
+	case unknownTypeReject:
+		s.notify(connection, fmt.Sprintf("Message not sent: message type %d is not supported by this server.", msg.Type))
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+// unknownType is a message type this server doesn't know, as a newer client could send.
+const unknownType = pb.MessageType(42)
+
+func TestUnknownTypePolicies(t *testing.T) {
+	tests := []struct {
+		policy    string
+		broadcast bool   // Whether the room receives the message
+		notice    string // What the sender is told, if anything
+	}{
+		{unknownTypeDrop, false, ""},
+		{unknownTypePassthrough, true, ""},
+		{unknownTypeReject, false, "Message not sent: message type 42 is not supported by this server."},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			config := testConfig()
+			config.UnknownTypePolicy = test.policy
+			ts := startServer(t, config, realClock{})
+			alice := ts.join(t, "alice")
+			bob := ts.join(t, "bob")
+			expectText(t, alice, "bob joined the room.")
+
+			if err := alice.Send(&pb.ChatMessage{Type: unknownType, Text: "poll: lunch?"}); err != nil {
+				t.Fatalf("sending: %v", err)
+			}
+			send(t, alice, "marker")
+
+			// What bob receives before the marker
+			msg := expectNext(t, bob)
+			if test.broadcast {
+				if msg.Text != "poll: lunch?" || msg.Type != unknownType {
+					t.Fatalf("bob received %v, want the message with its type kept", msg)
+				}
+				msg = expectNext(t, bob)
+			}
+			if msg.Text != "marker" {
+				t.Fatalf("bob received %v, want the marker", msg)
+			}
+
+			// What alice receives before the marker
+			msg = expectNext(t, alice)
+			if test.broadcast {
+				msg = expectNext(t, alice)
+			}
+			if test.notice != "" {
+				if msg.Text != test.notice {
+					t.Fatalf("alice received %v, want %q", msg, test.notice)
+				}
+				msg = expectNext(t, alice)
+			}
+			if msg.Text != "marker" {
+				t.Fatalf("alice received %v, want the marker", msg)
+			}
+		})
+	}
+}
+
+func TestUnknownTypesLoggedOnce(t *testing.T) {
+	seen := NewUnknownTypes()
+	if !seen.firstSeen(unknownType) {
+		t.Fatal("a new type wasn't logged")
+	}
+	if seen.firstSeen(unknownType) {
+		t.Fatal("a type was logged twice")
+	}
+
+	// Clients can send any value, the set stays bounded
+	for i := 0; i < 10*maxLoggedTypes; i++ {
+		seen.firstSeen(pb.MessageType(1000 + i))
+	}
+	if len(seen.logged) != maxLoggedTypes {
+		t.Fatalf("%d types remembered, want %d", len(seen.logged), maxLoggedTypes)
+	}
+}
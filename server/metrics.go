@@ -0,0 +1,31 @@
+package main
+
+// Metrics receives the server instrumentation, so deployments can plug in the
+// monitoring system of their choice. Implementations must be safe for concurrent use.
+type Metrics interface {
+	IncCounter(name string)              // Adds one to a counter
+	SetGauge(name string, value float64) // Sets a gauge to the given value
+	Observe(name string, value float64)  // Records a value in a histogram
+}
+
+// Names of the metrics reported by the server.
+const (
+	metricConnections      = "chat_connections"                   // Gauge: connected clients
+	metricConnectsRejected = "chat_connects_rejected_total"       // Counter: clients refused at Connect
+	metricMessagesReceived = "chat_messages_received_total"       // Counter: chat messages received from clients
+	metricBroadcastSeconds = "chat_broadcast_duration_seconds"    // Histogram: time spent queueing a message for every client
+	metricSendErrors       = "chat_send_errors_total"             // Counter: failed sends to a client stream
+	metricSendTimeouts     = "chat_send_timeouts_total"           // Counter: messages that couldn't be queued for a client in time
+	metricSlowDisconnects  = "chat_slow_client_disconnects_total" // Counter: clients dropped for timing out repeatedly
+	metricLoadShedding     = "chat_load_shedding"                 // Gauge: 1 while shedding load, 0 otherwise
+	metricProtectiveMode   = "chat_protective_mode"               // Gauge: 1 while sends fail en masse, 0 otherwise
+	metricMissedHeartbeats = "chat_heartbeat_disconnects_total"   // Counter: clients dropped for missing their heartbeat
+	metricUnknownTypes     = "chat_unknown_message_types_total"   // Counter: messages received with a type this server doesn't know
+)
+
+// NopMetrics discards everything, it is used when no metrics sink is configured.
+type NopMetrics struct{}
+
+func (NopMetrics) IncCounter(name string)              {}
+func (NopMetrics) SetGauge(name string, value float64) {}
+func (NopMetrics) Observe(name string, value float64)  {}
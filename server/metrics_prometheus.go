@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PrometheusMetrics reports the server metrics to a Prometheus registry.
+type PrometheusMetrics struct {
+	registry   *prometheus.Registry
+	counters   map[string]prometheus.Counter
+	gauges     map[string]prometheus.Gauge
+	histograms map[string]prometheus.Histogram
+}
+
+// NewPrometheusMetrics registers every server metric in a new registry.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry:   prometheus.NewRegistry(),
+		counters:   make(map[string]prometheus.Counter),
+		gauges:     make(map[string]prometheus.Gauge),
+		histograms: make(map[string]prometheus.Histogram),
+	}
+
+	m.counter(metricConnectsRejected, "Clients refused when connecting.")
+	m.counter(metricMessagesReceived, "Chat messages received from clients.")
+	m.counter(metricSendErrors, "Failed sends to a client stream.")
+	m.counter(metricSendTimeouts, "Messages that couldn't be queued for a client in time.")
+	m.counter(metricSlowDisconnects, "Clients disconnected for timing out repeatedly.")
+	m.counter(metricMissedHeartbeats, "Clients disconnected for missing their heartbeat.")
+	m.counter(metricUnknownTypes, "Messages received with a type this server doesn't know.")
+	m.gauge(metricConnections, "Connected clients.")
+	m.gauge(metricLoadShedding, "Whether the server is shedding load (1) or not (0).")
+	m.gauge(metricProtectiveMode, "Whether the server is in protective mode because of failing sends (1) or not (0).")
+	m.histogram(metricBroadcastSeconds, "Time spent queueing a message for every client.")
+
+	m.registry.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return m
+}
+
+func (m *PrometheusMetrics) counter(name, help string) {
+	m.counters[name] = prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: help})
+	m.registry.MustRegister(m.counters[name])
+}
+
+func (m *PrometheusMetrics) gauge(name, help string) {
+	m.gauges[name] = prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+	m.registry.MustRegister(m.gauges[name])
+}
+
+func (m *PrometheusMetrics) histogram(name, help string) {
+	m.histograms[name] = prometheus.NewHistogram(prometheus.HistogramOpts{Name: name, Help: help, Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10)})
+	m.registry.MustRegister(m.histograms[name])
+}
+
+// The maps are only written by NewPrometheusMetrics, so reading them needs no locking.
+// Unknown names are ignored.
+
+func (m *PrometheusMetrics) IncCounter(name string) {
+	if c, ok := m.counters[name]; ok {
+		c.Inc()
+	}
+}
+
+func (m *PrometheusMetrics) SetGauge(name string, value float64) {
+	if g, ok := m.gauges[name]; ok {
+		g.Set(value)
+	}
+}
+
+func (m *PrometheusMetrics) Observe(name string, value float64) {
+	if h, ok := m.histograms[name]; ok {
+		h.Observe(value)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
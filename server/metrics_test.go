@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+// recordingMetrics is a Metrics sink remembering what it was told.
+type recordingMetrics struct {
+	mutex        sync.Mutex
+	counters     map[string]int
+	gauges       map[string]float64
+	observations map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counters: make(map[string]int), gauges: make(map[string]float64), observations: make(map[string]int)}
+}
+
+func (m *recordingMetrics) IncCounter(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.counters[name]++
+}
+
+func (m *recordingMetrics) SetGauge(name string, value float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gauges[name] = value
+}
+
+func (m *recordingMetrics) Observe(name string, value float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.observations[name]++
+}
+
+func (m *recordingMetrics) counter(name string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.counters[name]
+}
+
+func (m *recordingMetrics) gauge(name string) float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.gauges[name]
+}
+
+func TestMetricsSink(t *testing.T) {
+	metrics := newRecordingMetrics()
+	config := testConfig()
+	config.SessionPolicy = sessionRejectNew
+	chatServer, err := NewChatServer(config, metrics, realClock{})
+	if err != nil {
+		t.Fatalf("NewChatServer: %v", err)
+	}
+	ts := serve(t, chatServer)
+
+	alice := ts.join(t, "alice")
+	ts.join(t, "bob")
+	if connections := metrics.gauge(metricConnections); connections != 2 {
+		t.Fatalf("%s is %v, want 2", metricConnections, connections)
+	}
+	expectCode(t, ts.open(t, "bob"), codes.AlreadyExists)
+	if rejected := metrics.counter(metricConnectsRejected); rejected != 1 {
+		t.Fatalf("%s is %d, want 1", metricConnectsRejected, rejected)
+	}
+
+	send(t, alice, "hello")
+	expectText(t, alice, "hello")
+	if received := metrics.counter(metricMessagesReceived); received != 1 {
+		t.Fatalf("%s is %d, want 1", metricMessagesReceived, received)
+	}
+	// The broadcast is timed once it queued the message for everyone
+	eventually(t, func() bool {
+		metrics.mutex.Lock()
+		defer metrics.mutex.Unlock()
+		return metrics.observations[metricBroadcastSeconds] > 0
+	})
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	metrics.IncCounter(metricMessagesReceived)
+	metrics.IncCounter(metricMessagesReceived)
+	metrics.SetGauge(metricConnections, 3)
+	metrics.Observe(metricBroadcastSeconds, 0.001)
+	metrics.IncCounter("chat_not_a_metric") // Ignored
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, want := range []string{
+		metricMessagesReceived + " 2",
+		metricConnections + " 3",
+		metricBroadcastSeconds + "_count 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %q", want)
+		}
+	}
+}
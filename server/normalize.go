@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// How aggressively incoming message text is normalized.
+const (
+	normalizeOff    = "off"    // Text is broadcast as sent
+	normalizeLight  = "light"  // NFKC, so look-alike forms such as fullwidth or math letters become plain ones, and collapsed spaces
+	normalizeStrict = "strict" // Like light, and excessive combining marks (zalgo text) are stripped
+)
+
+// maxCombiningMarks is how many combining marks a character keeps in strict normalization.
+// It is enough for legitimate scripts, such as Vietnamese, that stack two diacritics.
+const maxCombiningMarks = 2
+
+// normalizeText normalizes a text at the given level.
+func normalizeText(text, level string) string {
+	if level == normalizeOff {
+		return text
+	}
+
+	text = norm.NFKC.String(text)
+	if level == normalizeStrict {
+		text = stripCombiningMarks(text, maxCombiningMarks)
+	}
+	return collapseSpaces(text)
+}
+
+// collapseSpaces turns every run of spaces and tabs into a single space and trims the lines,
+// keeping the line breaks of multi-line messages.
+func collapseSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// stripCombiningMarks drops the combining marks beyond limit that follow a character.
+func stripCombiningMarks(text string, limit int) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	marks := 0
+	for _, r := range text {
+		if unicode.Is(unicode.Mn, r) {
+			marks++
+			if marks > limit {
+				continue
+			}
+		} else {
+			marks = 0
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
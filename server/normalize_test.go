@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	const zalgo = "a\u0336\u0337\u0338\u0335 b" // Four overlay marks on the a
+	tests := []struct {
+		level string
+		text  string
+		want  string
+	}{
+		{normalizeOff, "ｈｅｌｌｏ  world", "ｈｅｌｌｏ  world"},
+		{normalizeOff, zalgo, zalgo},
+		{normalizeLight, "ｈｅｌｌｏ  world", "hello world"},
+		{normalizeLight, "𝐛𝐨𝐥𝐝\tand  spaced ", "bold and spaced"},
+		// Line breaks are kept
+		{normalizeLight, "\nfirst  line \n\n\tsecond\r\n", "first line\n\nsecond"},
+		{normalizeLight, zalgo, zalgo},
+		{normalizeStrict, "ｈｅｌｌｏ  world", "hello world"},
+		{normalizeStrict, zalgo, "a\u0336\u0337 b"},
+		// Two stacked diacritics are legitimate
+		{normalizeStrict, "Vie\u0323\u0302t Nam", "Vi\u1ec7t Nam"},
+	}
+	for _, test := range tests {
+		if got := normalizeText(test.text, test.level); got != test.want {
+			t.Errorf("%s normalization of %q: got %q, want %q", test.level, test.text, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeBeforeBroadcast(t *testing.T) {
+	config := testConfig()
+	config.Normalize = normalizeStrict
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	send(t, alice, "ｈｉ   a\u0336\u0337\u0338")
+	expectText(t, alice, "hi a\u0336\u0337")
+	send(t, alice, "first\tline\nsecond  line")
+	expectText(t, alice, "first line\nsecond line")
+}
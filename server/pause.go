@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resumeNoticeTimeout is how long Resume waits for room in the queue to tell how many messages were missed.
+const resumeNoticeTimeout = time.Second
+
+// errPauseDisabled answers Pause and Resume when the server doesn't allow pausing.
+var errPauseDisabled = status.Error(codes.FailedPrecondition, "pausing is disabled on this server")
+
+// Pause stops delivering messages to the caller's chat stream, for instance while their app is in the background.
+// Messages keep being queued up to the send queue size, those beyond are dropped and counted as missed.
+// A message already on its way when the pause starts may still arrive.
+// The caller identifies with the same metadata as in Connect, plus the session token of the stream.
+// Pausing a paused connection changes nothing.
+func (s *ChatServer) Pause(ctx context.Context, req *pb.PauseRequest) (*pb.PauseState, error) {
+	if !s.config.Pause {
+		return nil, errPauseDisabled
+	}
+	connection, err := s.callerSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !connection.paused.Swap(true) {
+		log.Printf("Client '%s' paused.", connection.user)
+	}
+	return connection.pauseState(), nil
+}
+
+// Resume delivers the messages queued while the caller's chat stream was paused, then the new ones.
+// If messages were dropped, the client is told how many. Resuming a connection that isn't paused changes nothing.
+func (s *ChatServer) Resume(ctx context.Context, req *pb.ResumeRequest) (*pb.PauseState, error) {
+	if !s.config.Pause {
+		return nil, errPauseDisabled
+	}
+	connection, err := s.callerSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := connection.pauseState()
+	if connection.paused.Swap(false) {
+		log.Printf("Client '%s' resumed.", connection.user)
+		// Wake up the writer waiting for the resume
+		select {
+		case connection.resumed <- struct{}{}:
+		default:
+		}
+		// Messages were missed only if the queue filled up, so wait for the writer to make room
+		if missed := connection.missed.Swap(0); missed > 0 {
+			s.notifyWithin(connection, fmt.Sprintf("You missed %d messages while paused.", missed), resumeNoticeTimeout)
+		}
+		state.Paused = false
+	}
+	return state, nil
+}
+
+// pauseState describes whether a connection is paused and the messages held for it.
+func (c *Connection) pauseState() *pb.PauseState {
+	return &pb.PauseState{
+		Paused: c.paused.Load(),
+		Queued: uint32(len(c.outbox)),
+		Missed: c.missed.Load(),
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// sessionContext returns a context authenticating unary RPCs as user, with the session token of their stream.
+func sessionContext(t *testing.T, user string, stream pb.ChatService_ConnectClient) context.Context {
+	t.Helper()
+	header, err := stream.Header()
+	if err != nil {
+		t.Fatalf("reading the header of %s: %v", user, err)
+	}
+	token := header.Get(sessionTokenMetadataKey)
+	if len(token) != 1 || token[0] == "" {
+		t.Fatalf("header of %s has session token %q", user, token)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, user, sessionTokenMetadataKey, token[0])
+}
+
+func TestPauseAndResume(t *testing.T) {
+	config := testConfig()
+	config.SendQueueSize = 3
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+	ctx := sessionContext(t, "alice", alice)
+	connection := ts.connection(t, "alice")
+
+	received := make(chan *pb.ChatMessage, 16)
+	go func() {
+		for {
+			msg, err := alice.Recv()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- msg
+		}
+	}()
+
+	state, err := ts.client.Pause(ctx, &pb.PauseRequest{})
+	if err != nil || !state.Paused {
+		t.Fatalf("Pause returned %v (%v), want a paused state", state, err)
+	}
+
+	// A message already on its way when pausing may still arrive, after that everything waits
+	send(t, bob, "m0")
+	var delivered bool
+	eventually(t, func() bool {
+		select {
+		case <-received:
+			delivered = true
+		default:
+		}
+		return delivered || len(connection.outbox) == 1
+	})
+	var queued []string
+	if !delivered {
+		queued = append(queued, "m0")
+	}
+	for i := 1; i <= 4; i++ {
+		send(t, bob, fmt.Sprintf("m%d", i))
+		expectText(t, bob, fmt.Sprintf("m%d", i))
+		queued = append(queued, fmt.Sprintf("m%d", i))
+	}
+	if len(received) > 0 {
+		t.Fatalf("alice received %q while paused", (<-received).Text)
+	}
+
+	// The queue only kept the first messages, the others were missed
+	missed := uint64(len(queued) - config.SendQueueSize)
+	queued = queued[:config.SendQueueSize]
+	state, err = ts.client.Resume(ctx, &pb.ResumeRequest{})
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if state.Paused || state.Queued != uint32(len(queued)) || state.Missed != missed {
+		t.Fatalf("Resume returned %v, want %d queued and %d missed", state, len(queued), missed)
+	}
+
+	// The queued messages arrive in order, then the count of those missed
+	for _, text := range append(queued, fmt.Sprintf("You missed %d messages while paused.", missed)) {
+		if msg := <-received; msg == nil || msg.Text != text {
+			t.Fatalf("alice received %v, want %q", msg, text)
+		}
+	}
+}
+
+func TestPauseRequiresSessionToken(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	sessionContext(t, "alice", alice)
+
+	// Bob holds a token, but not the one of alice
+	bobCtx := sessionContext(t, "bob", bob)
+	md, _ := metadata.FromOutgoingContext(bobCtx)
+	md.Set(userMetadataKey, "alice")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"without token", metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, "alice"), codes.InvalidArgument},
+		{"with the token of another user", metadata.NewOutgoingContext(context.Background(), md), codes.PermissionDenied},
+		{"with a made-up token", metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, "alice", sessionTokenMetadataKey, "guess"), codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		if _, err := ts.client.Pause(test.ctx, &pb.PauseRequest{}); status.Code(err) != test.code {
+			t.Errorf("Pause %s returned %v, want %v", test.name, err, test.code)
+		}
+	}
+	if ts.connection(t, "alice").paused.Load() {
+		t.Fatal("alice was paused by someone else")
+	}
+}
+
+func TestPauseDisabled(t *testing.T) {
+	config := testConfig()
+	config.applySafeMode()
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+	ctx := sessionContext(t, "alice", alice)
+
+	if _, err := ts.client.Pause(ctx, &pb.PauseRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Pause in safe mode returned %v, want FailedPrecondition", err)
+	}
+	if _, err := ts.client.Resume(ctx, &pb.ResumeRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Resume in safe mode returned %v, want FailedPrecondition", err)
+	}
+}
+
+func TestSessionTokenBeforeAnyMessage(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	// A bot skipping system messages receives nothing on joining, but can pause right away
+	bot := ts.open(t, "bot", skipSystemMetadataKey, "true")
+	ts.connection(t, "bot")
+	if _, err := ts.client.Pause(sessionContext(t, "bot", bot), &pb.PauseRequest{}); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+}
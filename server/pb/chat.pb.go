@@ -22,13 +22,69 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type MessageType int32
+
+const (
+	MessageType_CHAT      MessageType = 0
+	MessageType_HEARTBEAT MessageType = 1
+	MessageType_SYSTEM    MessageType = 2
+)
+
+// Enum value maps for MessageType.
+var (
+	MessageType_name = map[int32]string{
+		0: "CHAT",
+		1: "HEARTBEAT",
+		2: "SYSTEM",
+	}
+	MessageType_value = map[string]int32{
+		"CHAT":      0,
+		"HEARTBEAT": 1,
+		"SYSTEM":    2,
+	}
+)
+
+func (x MessageType) Enum() *MessageType {
+	p := new(MessageType)
+	*p = x
+	return p
+}
+
+func (x MessageType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MessageType) Descriptor() protoreflect.EnumDescriptor {
+	return file_chat_proto_enumTypes[0].Descriptor()
+}
+
+func (MessageType) Type() protoreflect.EnumType {
+	return &file_chat_proto_enumTypes[0]
+}
+
+func (x MessageType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MessageType.Descriptor instead.
+func (MessageType) EnumDescriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{0}
+}
+
 type ChatMessage struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
-	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	User                  string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Text                  string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Timestamp             *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ProtocolVersion       uint32                 `protobuf:"varint,4,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Seq                   uint64                 `protobuf:"varint,5,opt,name=seq,proto3" json:"seq,omitempty"`
+	AttachmentUrl         string                 `protobuf:"bytes,6,opt,name=attachment_url,json=attachmentUrl,proto3" json:"attachment_url,omitempty"`
+	AttachmentContentType string                 `protobuf:"bytes,7,opt,name=attachment_content_type,json=attachmentContentType,proto3" json:"attachment_content_type,omitempty"`
+	AttachmentSize        uint64                 `protobuf:"varint,8,opt,name=attachment_size,json=attachmentSize,proto3" json:"attachment_size,omitempty"`
+	Type                  MessageType            `protobuf:"varint,9,opt,name=type,proto3,enum=chat.MessageType" json:"type,omitempty"`
+	Tags                  []string               `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
 }
 
 func (x *ChatMessage) Reset() {
@@ -82,18 +138,647 @@ func (x *ChatMessage) GetTimestamp() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *ChatMessage) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *ChatMessage) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ChatMessage) GetAttachmentUrl() string {
+	if x != nil {
+		return x.AttachmentUrl
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetAttachmentContentType() string {
+	if x != nil {
+		return x.AttachmentContentType
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetAttachmentSize() uint64 {
+	if x != nil {
+		return x.AttachmentSize
+	}
+	return 0
+}
+
+func (x *ChatMessage) GetType() MessageType {
+	if x != nil {
+		return x.Type
+	}
+	return MessageType_CHAT
+}
+
+func (x *ChatMessage) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type RoomStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomStatsRequest) Reset() {
+	*x = RoomStatsRequest{}
+	mi := &file_chat_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomStatsRequest) ProtoMessage() {}
+
+func (x *RoomStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomStatsRequest.ProtoReflect.Descriptor instead.
+func (*RoomStatsRequest) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{1}
+}
+
+type RoomStats struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MessageCount      uint64                 `protobuf:"varint,1,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`
+	ActiveUsers       uint32                 `protobuf:"varint,2,opt,name=active_users,json=activeUsers,proto3" json:"active_users,omitempty"`
+	MessagesPerMinute uint64                 `protobuf:"varint,3,opt,name=messages_per_minute,json=messagesPerMinute,proto3" json:"messages_per_minute,omitempty"`
+	LastActivity      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_activity,json=lastActivity,proto3" json:"last_activity,omitempty"`
+	Shedding          bool                   `protobuf:"varint,5,opt,name=shedding,proto3" json:"shedding,omitempty"`
+	ProtectiveMode    bool                   `protobuf:"varint,6,opt,name=protective_mode,json=protectiveMode,proto3" json:"protective_mode,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RoomStats) Reset() {
+	*x = RoomStats{}
+	mi := &file_chat_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomStats) ProtoMessage() {}
+
+func (x *RoomStats) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomStats.ProtoReflect.Descriptor instead.
+func (*RoomStats) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RoomStats) GetMessageCount() uint64 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *RoomStats) GetActiveUsers() uint32 {
+	if x != nil {
+		return x.ActiveUsers
+	}
+	return 0
+}
+
+func (x *RoomStats) GetMessagesPerMinute() uint64 {
+	if x != nil {
+		return x.MessagesPerMinute
+	}
+	return 0
+}
+
+func (x *RoomStats) GetLastActivity() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastActivity
+	}
+	return nil
+}
+
+func (x *RoomStats) GetShedding() bool {
+	if x != nil {
+		return x.Shedding
+	}
+	return false
+}
+
+func (x *RoomStats) GetProtectiveMode() bool {
+	if x != nil {
+		return x.ProtectiveMode
+	}
+	return false
+}
+
+type UserStatusesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []string               `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStatusesRequest) Reset() {
+	*x = UserStatusesRequest{}
+	mi := &file_chat_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatusesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatusesRequest) ProtoMessage() {}
+
+func (x *UserStatusesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatusesRequest.ProtoReflect.Descriptor instead.
+func (*UserStatusesRequest) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserStatusesRequest) GetUsers() []string {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type UserStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Online        bool                   `protobuf:"varint,2,opt,name=online,proto3" json:"online,omitempty"`
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStatus) Reset() {
+	*x = UserStatus{}
+	mi := &file_chat_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatus) ProtoMessage() {}
+
+func (x *UserStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatus.ProtoReflect.Descriptor instead.
+func (*UserStatus) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UserStatus) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *UserStatus) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *UserStatus) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+type UserStatuses struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Statuses      []*UserStatus          `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStatuses) Reset() {
+	*x = UserStatuses{}
+	mi := &file_chat_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatuses) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatuses) ProtoMessage() {}
+
+func (x *UserStatuses) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatuses.ProtoReflect.Descriptor instead.
+func (*UserStatuses) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UserStatuses) GetStatuses() []*UserStatus {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+type WhoAmIRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WhoAmIRequest) Reset() {
+	*x = WhoAmIRequest{}
+	mi := &file_chat_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WhoAmIRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WhoAmIRequest) ProtoMessage() {}
+
+func (x *WhoAmIRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WhoAmIRequest.ProtoReflect.Descriptor instead.
+func (*WhoAmIRequest) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{6}
+}
+
+type ConnectionInfo struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	User                string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	ConnectionId        uint64                 `protobuf:"varint,2,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	ProtocolVersion     uint32                 `protobuf:"varint,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	JoinedAt            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=joined_at,json=joinedAt,proto3" json:"joined_at,omitempty"`
+	AcceptedCompressors []string               `protobuf:"bytes,5,rep,name=accepted_compressors,json=acceptedCompressors,proto3" json:"accepted_compressors,omitempty"`
+	SkipSystemMessages  bool                   `protobuf:"varint,6,opt,name=skip_system_messages,json=skipSystemMessages,proto3" json:"skip_system_messages,omitempty"`
+	QueueLimit          uint32                 `protobuf:"varint,7,opt,name=queue_limit,json=queueLimit,proto3" json:"queue_limit,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ConnectionInfo) Reset() {
+	*x = ConnectionInfo{}
+	mi := &file_chat_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionInfo) ProtoMessage() {}
+
+func (x *ConnectionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionInfo.ProtoReflect.Descriptor instead.
+func (*ConnectionInfo) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ConnectionInfo) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ConnectionInfo) GetConnectionId() uint64 {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetJoinedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.JoinedAt
+	}
+	return nil
+}
+
+func (x *ConnectionInfo) GetAcceptedCompressors() []string {
+	if x != nil {
+		return x.AcceptedCompressors
+	}
+	return nil
+}
+
+func (x *ConnectionInfo) GetSkipSystemMessages() bool {
+	if x != nil {
+		return x.SkipSystemMessages
+	}
+	return false
+}
+
+func (x *ConnectionInfo) GetQueueLimit() uint32 {
+	if x != nil {
+		return x.QueueLimit
+	}
+	return 0
+}
+
+type PauseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseRequest) Reset() {
+	*x = PauseRequest{}
+	mi := &file_chat_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseRequest) ProtoMessage() {}
+
+func (x *PauseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseRequest.ProtoReflect.Descriptor instead.
+func (*PauseRequest) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{8}
+}
+
+type ResumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_chat_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{9}
+}
+
+type PauseState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Paused        bool                   `protobuf:"varint,1,opt,name=paused,proto3" json:"paused,omitempty"`
+	Queued        uint32                 `protobuf:"varint,2,opt,name=queued,proto3" json:"queued,omitempty"`
+	Missed        uint64                 `protobuf:"varint,3,opt,name=missed,proto3" json:"missed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseState) Reset() {
+	*x = PauseState{}
+	mi := &file_chat_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseState) ProtoMessage() {}
+
+func (x *PauseState) ProtoReflect() protoreflect.Message {
+	mi := &file_chat_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseState.ProtoReflect.Descriptor instead.
+func (*PauseState) Descriptor() ([]byte, []int) {
+	return file_chat_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PauseState) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *PauseState) GetQueued() uint32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *PauseState) GetMissed() uint64 {
+	if x != nil {
+		return x.Missed
+	}
+	return 0
+}
+
 var File_chat_proto protoreflect.FileDescriptor
 
 const file_chat_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"chat.proto\x12\x04chat\x1a\x1fgoogle/protobuf/timestamp.proto\"o\n" +
+	"chat.proto\x12\x04chat\x1a\x1fgoogle/protobuf/timestamp.proto\"\xef\x02\n" +
 	"\vChatMessage\x12\x12\n" +
 	"\x04user\x18\x01 \x01(\tR\x04user\x12\x12\n" +
 	"\x04text\x18\x02 \x01(\tR\x04text\x128\n" +
-	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp2B\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12)\n" +
+	"\x10protocol_version\x18\x04 \x01(\rR\x0fprotocolVersion\x12\x10\n" +
+	"\x03seq\x18\x05 \x01(\x04R\x03seq\x12%\n" +
+	"\x0eattachment_url\x18\x06 \x01(\tR\rattachmentUrl\x126\n" +
+	"\x17attachment_content_type\x18\a \x01(\tR\x15attachmentContentType\x12'\n" +
+	"\x0fattachment_size\x18\b \x01(\x04R\x0eattachmentSize\x12%\n" +
+	"\x04type\x18\t \x01(\x0e2\x11.chat.MessageTypeR\x04type\x12\x12\n" +
+	"\x04tags\x18\n" +
+	" \x03(\tR\x04tags\"\x12\n" +
+	"\x10RoomStatsRequest\"\x89\x02\n" +
+	"\tRoomStats\x12#\n" +
+	"\rmessage_count\x18\x01 \x01(\x04R\fmessageCount\x12!\n" +
+	"\factive_users\x18\x02 \x01(\rR\vactiveUsers\x12.\n" +
+	"\x13messages_per_minute\x18\x03 \x01(\x04R\x11messagesPerMinute\x12?\n" +
+	"\rlast_activity\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\flastActivity\x12\x1a\n" +
+	"\bshedding\x18\x05 \x01(\bR\bshedding\x12'\n" +
+	"\x0fprotective_mode\x18\x06 \x01(\bR\x0eprotectiveMode\"+\n" +
+	"\x13UserStatusesRequest\x12\x14\n" +
+	"\x05users\x18\x01 \x03(\tR\x05users\"q\n" +
+	"\n" +
+	"UserStatus\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x16\n" +
+	"\x06online\x18\x02 \x01(\bR\x06online\x127\n" +
+	"\tlast_seen\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\"<\n" +
+	"\fUserStatuses\x12,\n" +
+	"\bstatuses\x18\x01 \x03(\v2\x10.chat.UserStatusR\bstatuses\"\x0f\n" +
+	"\rWhoAmIRequest\"\xb3\x02\n" +
+	"\x0eConnectionInfo\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12#\n" +
+	"\rconnection_id\x18\x02 \x01(\x04R\fconnectionId\x12)\n" +
+	"\x10protocol_version\x18\x03 \x01(\rR\x0fprotocolVersion\x127\n" +
+	"\tjoined_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bjoinedAt\x121\n" +
+	"\x14accepted_compressors\x18\x05 \x03(\tR\x13acceptedCompressors\x120\n" +
+	"\x14skip_system_messages\x18\x06 \x01(\bR\x12skipSystemMessages\x12\x1f\n" +
+	"\vqueue_limit\x18\a \x01(\rR\n" +
+	"queueLimit\"\x0e\n" +
+	"\fPauseRequest\"\x0f\n" +
+	"\rResumeRequest\"T\n" +
+	"\n" +
+	"PauseState\x12\x16\n" +
+	"\x06paused\x18\x01 \x01(\bR\x06paused\x12\x16\n" +
+	"\x06queued\x18\x02 \x01(\rR\x06queued\x12\x16\n" +
+	"\x06missed\x18\x03 \x01(\x04R\x06missed*2\n" +
+	"\vMessageType\x12\b\n" +
+	"\x04CHAT\x10\x00\x12\r\n" +
+	"\tHEARTBEAT\x10\x01\x12\n" +
+	"\n" +
+	"\x06SYSTEM\x10\x022\xd2\x02\n" +
 	"\vChatService\x123\n" +
-	"\aConnect\x12\x11.chat.ChatMessage\x1a\x11.chat.ChatMessage(\x010\x01B1Z/github.com/artursilveiradev/grpc-chat/server/pbb\x06proto3"
+	"\aConnect\x12\x11.chat.ChatMessage\x1a\x11.chat.ChatMessage(\x010\x01\x127\n" +
+	"\fGetRoomStats\x12\x16.chat.RoomStatsRequest\x1a\x0f.chat.RoomStats\x12@\n" +
+	"\x0fGetUserStatuses\x12\x19.chat.UserStatusesRequest\x1a\x12.chat.UserStatuses\x123\n" +
+	"\x06WhoAmI\x12\x13.chat.WhoAmIRequest\x1a\x14.chat.ConnectionInfo\x12-\n" +
+	"\x05Pause\x12\x12.chat.PauseRequest\x1a\x10.chat.PauseState\x12/\n" +
+	"\x06Resume\x12\x13.chat.ResumeRequest\x1a\x10.chat.PauseStateB1Z/github.com/artursilveiradev/grpc-chat/server/pbb\x06proto3"
 
 var (
 	file_chat_proto_rawDescOnce sync.Once
@@ -107,20 +792,47 @@ func file_chat_proto_rawDescGZIP() []byte {
 	return file_chat_proto_rawDescData
 }
 
-var file_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_chat_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_chat_proto_goTypes = []any{
-	(*ChatMessage)(nil),           // 0: chat.ChatMessage
-	(*timestamppb.Timestamp)(nil), // 1: google.protobuf.Timestamp
+	(MessageType)(0),              // 0: chat.MessageType
+	(*ChatMessage)(nil),           // 1: chat.ChatMessage
+	(*RoomStatsRequest)(nil),      // 2: chat.RoomStatsRequest
+	(*RoomStats)(nil),             // 3: chat.RoomStats
+	(*UserStatusesRequest)(nil),   // 4: chat.UserStatusesRequest
+	(*UserStatus)(nil),            // 5: chat.UserStatus
+	(*UserStatuses)(nil),          // 6: chat.UserStatuses
+	(*WhoAmIRequest)(nil),         // 7: chat.WhoAmIRequest
+	(*ConnectionInfo)(nil),        // 8: chat.ConnectionInfo
+	(*PauseRequest)(nil),          // 9: chat.PauseRequest
+	(*ResumeRequest)(nil),         // 10: chat.ResumeRequest
+	(*PauseState)(nil),            // 11: chat.PauseState
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
 }
 var file_chat_proto_depIdxs = []int32{
-	1, // 0: chat.ChatMessage.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 1: chat.ChatService.Connect:input_type -> chat.ChatMessage
-	0, // 2: chat.ChatService.Connect:output_type -> chat.ChatMessage
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	12, // 0: chat.ChatMessage.timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 1: chat.ChatMessage.type:type_name -> chat.MessageType
+	12, // 2: chat.RoomStats.last_activity:type_name -> google.protobuf.Timestamp
+	12, // 3: chat.UserStatus.last_seen:type_name -> google.protobuf.Timestamp
+	5,  // 4: chat.UserStatuses.statuses:type_name -> chat.UserStatus
+	12, // 5: chat.ConnectionInfo.joined_at:type_name -> google.protobuf.Timestamp
+	1,  // 6: chat.ChatService.Connect:input_type -> chat.ChatMessage
+	2,  // 7: chat.ChatService.GetRoomStats:input_type -> chat.RoomStatsRequest
+	4,  // 8: chat.ChatService.GetUserStatuses:input_type -> chat.UserStatusesRequest
+	7,  // 9: chat.ChatService.WhoAmI:input_type -> chat.WhoAmIRequest
+	9,  // 10: chat.ChatService.Pause:input_type -> chat.PauseRequest
+	10, // 11: chat.ChatService.Resume:input_type -> chat.ResumeRequest
+	1,  // 12: chat.ChatService.Connect:output_type -> chat.ChatMessage
+	3,  // 13: chat.ChatService.GetRoomStats:output_type -> chat.RoomStats
+	6,  // 14: chat.ChatService.GetUserStatuses:output_type -> chat.UserStatuses
+	8,  // 15: chat.ChatService.WhoAmI:output_type -> chat.ConnectionInfo
+	11, // 16: chat.ChatService.Pause:output_type -> chat.PauseState
+	11, // 17: chat.ChatService.Resume:output_type -> chat.PauseState
+	12, // [12:18] is the sub-list for method output_type
+	6,  // [6:12] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_chat_proto_init() }
@@ -133,13 +845,14 @@ func file_chat_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_chat_proto_rawDesc), len(file_chat_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   1,
+			NumEnums:      1,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_chat_proto_goTypes,
 		DependencyIndexes: file_chat_proto_depIdxs,
+		EnumInfos:         file_chat_proto_enumTypes,
 		MessageInfos:      file_chat_proto_msgTypes,
 	}.Build()
 	File_chat_proto = out.File
@@ -19,7 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ChatService_Connect_FullMethodName = "/chat.ChatService/Connect"
+	ChatService_Connect_FullMethodName         = "/chat.ChatService/Connect"
+	ChatService_GetRoomStats_FullMethodName    = "/chat.ChatService/GetRoomStats"
+	ChatService_GetUserStatuses_FullMethodName = "/chat.ChatService/GetUserStatuses"
+	ChatService_WhoAmI_FullMethodName          = "/chat.ChatService/WhoAmI"
+	ChatService_Pause_FullMethodName           = "/chat.ChatService/Pause"
+	ChatService_Resume_FullMethodName          = "/chat.ChatService/Resume"
 )
 
 // ChatServiceClient is the client API for ChatService service.
@@ -27,6 +32,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatServiceClient interface {
 	Connect(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ChatMessage, ChatMessage], error)
+	GetRoomStats(ctx context.Context, in *RoomStatsRequest, opts ...grpc.CallOption) (*RoomStats, error)
+	GetUserStatuses(ctx context.Context, in *UserStatusesRequest, opts ...grpc.CallOption) (*UserStatuses, error)
+	WhoAmI(ctx context.Context, in *WhoAmIRequest, opts ...grpc.CallOption) (*ConnectionInfo, error)
+	Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseState, error)
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*PauseState, error)
 }
 
 type chatServiceClient struct {
@@ -50,11 +60,66 @@ func (c *chatServiceClient) Connect(ctx context.Context, opts ...grpc.CallOption
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ChatService_ConnectClient = grpc.BidiStreamingClient[ChatMessage, ChatMessage]
 
+func (c *chatServiceClient) GetRoomStats(ctx context.Context, in *RoomStatsRequest, opts ...grpc.CallOption) (*RoomStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoomStats)
+	err := c.cc.Invoke(ctx, ChatService_GetRoomStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) GetUserStatuses(ctx context.Context, in *UserStatusesRequest, opts ...grpc.CallOption) (*UserStatuses, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserStatuses)
+	err := c.cc.Invoke(ctx, ChatService_GetUserStatuses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) WhoAmI(ctx context.Context, in *WhoAmIRequest, opts ...grpc.CallOption) (*ConnectionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConnectionInfo)
+	err := c.cc.Invoke(ctx, ChatService_WhoAmI_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseState)
+	err := c.cc.Invoke(ctx, ChatService_Pause_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*PauseState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseState)
+	err := c.cc.Invoke(ctx, ChatService_Resume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServiceServer is the server API for ChatService service.
 // All implementations must embed UnimplementedChatServiceServer
 // for forward compatibility.
 type ChatServiceServer interface {
 	Connect(grpc.BidiStreamingServer[ChatMessage, ChatMessage]) error
+	GetRoomStats(context.Context, *RoomStatsRequest) (*RoomStats, error)
+	GetUserStatuses(context.Context, *UserStatusesRequest) (*UserStatuses, error)
+	WhoAmI(context.Context, *WhoAmIRequest) (*ConnectionInfo, error)
+	Pause(context.Context, *PauseRequest) (*PauseState, error)
+	Resume(context.Context, *ResumeRequest) (*PauseState, error)
 	mustEmbedUnimplementedChatServiceServer()
 }
 
@@ -68,6 +133,21 @@ type UnimplementedChatServiceServer struct{}
 func (UnimplementedChatServiceServer) Connect(grpc.BidiStreamingServer[ChatMessage, ChatMessage]) error {
 	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
+func (UnimplementedChatServiceServer) GetRoomStats(context.Context, *RoomStatsRequest) (*RoomStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoomStats not implemented")
+}
+func (UnimplementedChatServiceServer) GetUserStatuses(context.Context, *UserStatusesRequest) (*UserStatuses, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserStatuses not implemented")
+}
+func (UnimplementedChatServiceServer) WhoAmI(context.Context, *WhoAmIRequest) (*ConnectionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WhoAmI not implemented")
+}
+func (UnimplementedChatServiceServer) Pause(context.Context, *PauseRequest) (*PauseState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedChatServiceServer) Resume(context.Context, *ResumeRequest) (*PauseState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
 func (UnimplementedChatServiceServer) mustEmbedUnimplementedChatServiceServer() {}
 func (UnimplementedChatServiceServer) testEmbeddedByValue()                     {}
 
@@ -96,13 +176,124 @@ func _ChatService_Connect_Handler(srv interface{}, stream grpc.ServerStream) err
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ChatService_ConnectServer = grpc.BidiStreamingServer[ChatMessage, ChatMessage]
 
+func _ChatService_GetRoomStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetRoomStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_GetRoomStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetRoomStats(ctx, req.(*RoomStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_GetUserStatuses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserStatusesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetUserStatuses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_GetUserStatuses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetUserStatuses(ctx, req.(*UserStatusesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_WhoAmI_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhoAmIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).WhoAmI(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_WhoAmI_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).WhoAmI(ctx, req.(*WhoAmIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Pause(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChatService_Resume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Resume(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChatService_ServiceDesc is the grpc.ServiceDesc for ChatService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var ChatService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "chat.ChatService",
 	HandlerType: (*ChatServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRoomStats",
+			Handler:    _ChatService_GetRoomStats_Handler,
+		},
+		{
+			MethodName: "GetUserStatuses",
+			Handler:    _ChatService_GetUserStatuses_Handler,
+		},
+		{
+			MethodName: "WhoAmI",
+			Handler:    _ChatService_WhoAmI_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _ChatService_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _ChatService_Resume_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Connect",
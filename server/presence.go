@@ -0,0 +1,200 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetUserStatuses tells, for each requested user, whether they are online and when they were last seen.
+// The last seen time is only known for users who left since the server started, up to maxLastSeen of them.
+func (s *ChatServer) GetUserStatuses(ctx context.Context, req *pb.UserStatusesRequest) (*pb.UserStatuses, error) {
+	if len(req.Users) > s.config.MaxStatusQuery {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d users can be queried at once", s.config.MaxStatusQuery)
+	}
+
+	s.mutex.RLock() // Snapshot all the users at the same moment
+	defer s.mutex.RUnlock()
+
+	statuses := make([]*pb.UserStatus, 0, len(req.Users))
+	for _, user := range req.Users {
+		userStatus := &pb.UserStatus{User: user}
+		if _, ok := s.connections[user]; ok {
+			userStatus.Online = true
+		} else if lastSeen, ok := s.lastSeen.get(user); ok {
+			userStatus.LastSeen = timestamppb.New(lastSeen)
+		}
+		statuses = append(statuses, userStatus)
+	}
+	return &pb.UserStatuses{Statuses: statuses}, nil
+}
+
+// maxLastSeen caps how many users who left are remembered for GetUserStatuses.
+// Clients choose the names, so the record must not grow without bounds.
+const maxLastSeen = 10000
+
+// LastSeen remembers when users left, forgetting those who left longest ago beyond a maximum.
+// It is protected by the mutex of the server.
+type LastSeen struct {
+	max   int                      // Users remembered at most
+	order *list.List               // Departures, oldest first
+	users map[string]*list.Element // Departure of each user in order
+}
+
+// departure is when a user left.
+type departure struct {
+	user string
+	at   time.Time
+}
+
+// NewLastSeen creates an empty record remembering at most max users.
+func NewLastSeen(max int) *LastSeen {
+	return &LastSeen{max: max, order: list.New(), users: make(map[string]*list.Element)}
+}
+
+// record notes that user left at, forgetting the oldest departure if the record is full.
+func (l *LastSeen) record(user string, at time.Time) {
+	if element, ok := l.users[user]; ok {
+		element.Value = departure{user: user, at: at}
+		l.order.MoveToBack(element)
+		return
+	}
+	l.users[user] = l.order.PushBack(departure{user: user, at: at})
+	if l.order.Len() > l.max {
+		oldest := l.order.Remove(l.order.Front()).(departure)
+		delete(l.users, oldest.user)
+	}
+}
+
+// get returns when user left, if they are remembered.
+func (l *LastSeen) get(user string) (time.Time, bool) {
+	element, ok := l.users[user]
+	if !ok {
+		return time.Time{}, false
+	}
+	return element.Value.(departure).at, true
+}
+
+// maxSnapshotNames is how many users a presence snapshot names for each of joins and leaves.
+const maxSnapshotNames = 20
+
+// PresenceThrottle stops announcing every join and leave once the room is large, since each one
+// is broadcast to everybody. Changes are then folded into periodic snapshots, or only
+// available through GetUserStatuses if snapshots are disabled.
+type PresenceThrottle struct {
+	threshold int           // Active users above which announcements are folded (0 disables)
+	interval  time.Duration // How often folded changes are announced (0 never does)
+	mutex     sync.Mutex    // Mutex to protect joined and left
+	joined    []string      // Users who joined since the last snapshot
+	left      []string      // Users who left since the last snapshot
+}
+
+// NewPresenceThrottle creates a throttle with the threshold and interval from the configuration.
+func NewPresenceThrottle(config Config) *PresenceThrottle {
+	return &PresenceThrottle{
+		threshold: config.PresenceThreshold,
+		interval:  config.PresenceSnapshotInterval,
+	}
+}
+
+// snapshots reports whether folded changes are periodically announced.
+func (p *PresenceThrottle) snapshots() bool {
+	return p.threshold > 0 && p.interval > 0
+}
+
+// fold reports whether a presence change in a room of the given size must not be announced on its own,
+// keeping it for the next snapshot.
+func (p *PresenceThrottle) fold(user string, joined bool, activeUsers int) bool {
+	if p.threshold <= 0 || activeUsers <= p.threshold {
+		return false
+	}
+	if !p.snapshots() {
+		return true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if joined {
+		p.joined = append(p.joined, user)
+	} else {
+		p.left = append(p.left, user)
+	}
+	return true
+}
+
+// drain returns the changes folded since the last call.
+func (p *PresenceThrottle) drain() (joined, left []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	joined, left = p.joined, p.left
+	p.joined, p.left = nil, nil
+	return joined, left
+}
+
+// announcePresence lets everyone know a user joined or left, unless the room is large enough for it to be folded.
+func (s *ChatServer) announcePresence(user string, joined bool) {
+	s.mutex.RLock()
+	activeUsers := len(s.connections)
+	s.mutex.RUnlock()
+	if s.presence.fold(user, joined, activeUsers) {
+		return
+	}
+
+	text := fmt.Sprintf("%s left the room.", user)
+	if joined {
+		text = fmt.Sprintf("%s joined the room.", user)
+	}
+	s.publish(&pb.ChatMessage{
+		User:      systemUser,
+		Type:      pb.MessageType_SYSTEM,
+		Text:      text,
+		Timestamp: timestamppb.New(s.clock.Now()),
+	})
+}
+
+// runPresenceSnapshots announces the folded presence changes at every interval, for as long as the server lives.
+func (s *ChatServer) runPresenceSnapshots() {
+	for {
+		timer := s.clock.NewTimer(s.presence.interval)
+		<-timer.C()
+
+		joined, left := s.presence.drain()
+		if len(joined) == 0 && len(left) == 0 {
+			continue
+		}
+		s.mutex.RLock()
+		activeUsers := len(s.connections)
+		s.mutex.RUnlock()
+
+		text := fmt.Sprintf("%d users online.", activeUsers)
+		if len(joined) > 0 {
+			text += " Joined: " + listNames(joined) + "."
+		}
+		if len(left) > 0 {
+			text += " Left: " + listNames(left) + "."
+		}
+		s.publish(&pb.ChatMessage{
+			User:      systemUser,
+			Type:      pb.MessageType_SYSTEM,
+			Text:      text,
+			Timestamp: timestamppb.New(s.clock.Now()),
+		})
+	}
+}
+
+// listNames formats users for a snapshot, naming at most maxSnapshotNames of them.
+func listNames(users []string) string {
+	if len(users) <= maxSnapshotNames {
+		return strings.Join(users, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(users[:maxSnapshotNames], ", "), len(users)-maxSnapshotNames)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetUserStatuses(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.MaxStatusQuery = 3
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+
+	clock.Advance(time.Minute)
+	left := clock.Now()
+	bob.CloseSend()
+	expectText(t, alice, "bob left the room.")
+
+	statuses, err := ts.client.GetUserStatuses(context.Background(), &pb.UserStatusesRequest{Users: []string{"alice", "bob", "carol"}})
+	if err != nil {
+		t.Fatalf("GetUserStatuses: %v", err)
+	}
+	if len(statuses.Statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses.Statuses))
+	}
+	if s := statuses.Statuses[0]; s.User != "alice" || !s.Online || s.LastSeen != nil {
+		t.Errorf("status of alice: %v, want online", s)
+	}
+	if s := statuses.Statuses[1]; s.User != "bob" || s.Online || !s.LastSeen.AsTime().Equal(left) {
+		t.Errorf("status of bob: %v, want offline since %v", s, left)
+	}
+	if s := statuses.Statuses[2]; s.User != "carol" || s.Online || s.LastSeen != nil {
+		t.Errorf("status of carol: %v, want never seen", s)
+	}
+
+	_, err = ts.client.GetUserStatuses(context.Background(), &pb.UserStatusesRequest{Users: []string{"a", "b", "c", "d"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("querying 4 users returned %v, want InvalidArgument", err)
+	}
+}
+
+func TestLastSeenIsBounded(t *testing.T) {
+	lastSeen := NewLastSeen(2)
+	now := time.Now()
+	for i, user := range []string{"alice", "bob", "carol"} {
+		lastSeen.record(user, now.Add(time.Duration(i)*time.Second))
+	}
+	if _, ok := lastSeen.get("alice"); ok {
+		t.Error("alice is still remembered, who left first")
+	}
+
+	// Leaving again makes bob the latest departure
+	lastSeen.record("bob", now.Add(3*time.Second))
+	lastSeen.record("dave", now.Add(4*time.Second))
+	if _, ok := lastSeen.get("carol"); ok {
+		t.Error("carol is still remembered, who left longest ago")
+	}
+	for user, want := range map[string]time.Time{"bob": now.Add(3 * time.Second), "dave": now.Add(4 * time.Second)} {
+		if at, ok := lastSeen.get(user); !ok || !at.Equal(want) {
+			t.Errorf("%s last seen at %v (%t), want %v", user, at, ok, want)
+		}
+	}
+}
+
+func TestPresenceThrottle(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.PresenceThreshold = 2
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	ts.join(t, "bob")
+	expectText(t, alice, "bob joined the room.")
+
+	// Above the threshold the changes wait for the snapshot
+	ts.open(t, "carol")
+	ts.connection(t, "carol")
+	dave := ts.open(t, "dave")
+	ts.connection(t, "dave")
+	dave.CloseSend()
+	eventually(t, func() bool {
+		ts.mutex.RLock()
+		defer ts.mutex.RUnlock()
+		_, left := ts.lastSeen.get("dave")
+		return left
+	})
+
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Minute)
+	const snapshot = "3 users online. Joined: carol, dave. Left: dave."
+	for {
+		msg := expectNext(t, alice)
+		if msg.Text == snapshot {
+			break
+		}
+		if msg.Type == pb.MessageType_SYSTEM {
+			t.Fatalf("got %q before the snapshot", msg.Text)
+		}
+	}
+
+	// Nothing changed since, so the next interval is quiet
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Minute)
+	send(t, alice, "marker")
+	if msg := expectNext(t, alice); msg.Text != "marker" {
+		t.Fatalf("got %q after a quiet interval, want the marker", msg.Text)
+	}
+}
+
+func TestListNames(t *testing.T) {
+	users := make([]string, maxSnapshotNames+3)
+	for i := range users {
+		users[i] = "u"
+	}
+	if got := listNames(users[:2]); got != "u, u" {
+		t.Errorf("listing 2 users: got %q", got)
+	}
+	if got := listNames(users); !strings.HasSuffix(got, "u and 3 more") || strings.Count(got, "u") != maxSnapshotNames {
+		t.Errorf("listing %d users: got %q", len(users), got)
+	}
+}
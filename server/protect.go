@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// minSendSamples is the fewest sends in a window for its failure rate to be meaningful.
+const minSendSamples = 20
+
+// SendMonitor watches the rate of failed sends across all connections. When sends
+// fail en masse the process is likely running out of file descriptors or memory, so
+// the monitor enters protective mode, in which new connections are refused.
+type SendMonitor struct {
+	threshold  float64      // Failure rate from which we turn protective (0 disables)
+	attempts   atomic.Int64 // Sends attempted in the current window
+	failures   atomic.Int64 // Sends failed in the current window
+	protective atomic.Bool  // Whether we are in protective mode
+	metrics    Metrics      // Receives the protective mode state
+}
+
+// NewSendMonitor creates a monitor with the threshold from the configuration.
+func NewSendMonitor(config Config, metrics Metrics) *SendMonitor {
+	return &SendMonitor{
+		threshold: config.SendFailureThreshold,
+		metrics:   metrics,
+	}
+}
+
+// enabled reports whether a threshold is configured.
+func (m *SendMonitor) enabled() bool {
+	return m.threshold > 0
+}
+
+// Protective reports whether new connections should currently be refused.
+func (m *SendMonitor) Protective() bool {
+	return m.protective.Load()
+}
+
+// record counts the outcome of a send.
+func (m *SendMonitor) record(err error) {
+	m.attempts.Add(1)
+	if err != nil {
+		m.failures.Add(1)
+	}
+}
+
+// run evaluates the failure rate at the end of every window of clock, for as long as the server lives.
+func (m *SendMonitor) run(clock Clock, window time.Duration) {
+	for {
+		timer := clock.NewTimer(window)
+		<-timer.C()
+		m.check(m.attempts.Swap(0), m.failures.Swap(0))
+	}
+}
+
+// check updates the protective mode from the sends of a window, logging every transition.
+// Windows with too few sends to judge clear the mode: while new clients are refused the
+// traffic may dry up, and staying protective on no evidence would lock everyone out.
+func (m *SendMonitor) check(attempts, failures int64) {
+	protective := false
+	if attempts >= minSendSamples {
+		protective = float64(failures)/float64(attempts) >= m.threshold
+	}
+	if m.protective.Swap(protective) != protective {
+		if protective {
+			m.metrics.SetGauge(metricProtectiveMode, 1)
+			log.Printf("Protective mode engaged: %d of %d sends failed. Refusing new clients.", failures, attempts)
+		} else {
+			m.metrics.SetGauge(metricProtectiveMode, 0)
+			log.Println("Protective mode disengaged.")
+		}
+	}
+}
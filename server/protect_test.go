@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSendMonitorProtectiveMode(t *testing.T) {
+	monitor := NewSendMonitor(Config{SendFailureThreshold: 0.5}, NopMetrics{})
+
+	// Most sends of the window fail, as when the process runs out of file descriptors
+	for i := 0; i < 30; i++ {
+		var err error
+		if i%3 != 0 {
+			err = errors.New("too many open files")
+		}
+		monitor.record(err)
+	}
+	monitor.check(monitor.attempts.Swap(0), monitor.failures.Swap(0))
+	if !monitor.Protective() {
+		t.Fatal("20 failures out of 30 sends didn't engage protective mode")
+	}
+
+	// Sends recover
+	monitor.check(40, 2)
+	if monitor.Protective() {
+		t.Fatal("2 failures out of 40 sends didn't disengage protective mode")
+	}
+}
+
+func TestSendMonitorLeavesProtectiveModeWhenSendsDryUp(t *testing.T) {
+	monitor := NewSendMonitor(Config{SendFailureThreshold: 0.5}, NopMetrics{})
+
+	monitor.check(20, 20)
+	if !monitor.Protective() {
+		t.Fatal("20 failed sends didn't engage protective mode")
+	}
+	// New clients are refused, so there is nothing left to send
+	for i := 0; i < 5; i++ {
+		monitor.check(0, 0)
+	}
+	if monitor.Protective() {
+		t.Fatal("windows without sends kept protective mode on")
+	}
+}
+
+func TestSendMonitorIgnoresFewFailures(t *testing.T) {
+	monitor := NewSendMonitor(Config{SendFailureThreshold: 0.5}, NopMetrics{})
+
+	monitor.check(minSendSamples-1, minSendSamples-1)
+	if monitor.Protective() {
+		t.Fatal("a window with too few sends engaged protective mode")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuotaTracker counts the messages each user sent in the current quota period.
+// A period lasts one day and starts at a configured time of day in a configured timezone.
+type QuotaTracker struct {
+	limit    int            // Messages a user may send per period (0 disables)
+	resetAt  time.Duration  // Time of day the period starts, as an offset from midnight
+	location *time.Location // Timezone the time of day is read in
+	mutex    sync.Mutex     // Mutex to protect the counters
+	period   time.Time      // Start of the period the counts refer to
+	counts   map[string]int // Messages sent per user in the period
+}
+
+// NewQuotaTracker creates a tracker with the quota from the configuration.
+func NewQuotaTracker(config Config) *QuotaTracker {
+	// parseFlags already rejected unknown timezones
+	location, err := time.LoadLocation(config.QuotaTimezone)
+	if err != nil {
+		location = time.Local
+	}
+	return &QuotaTracker{
+		limit:    config.DailyQuota,
+		resetAt:  config.QuotaResetAt,
+		location: location,
+		counts:   make(map[string]int),
+	}
+}
+
+// periodStart returns when the period containing now started.
+func (q *QuotaTracker) periodStart(now time.Time) time.Time {
+	local := now.In(q.location)
+	year, month, day := local.Date()
+	hour, minute := int(q.resetAt/time.Hour), int(q.resetAt%time.Hour/time.Minute)
+	start := time.Date(year, month, day, hour, minute, 0, 0, q.location)
+	if local.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// startPeriod clears the counts when now is in a new period. It must be called with the mutex held.
+func (q *QuotaTracker) startPeriod(now time.Time) {
+	// Everybody starts over when a new period begins
+	if start := q.periodStart(now); !start.Equal(q.period) {
+		q.period = start
+		clear(q.counts)
+	}
+}
+
+// allow reports whether user may send another message at now. Once the user has used up the quota
+// it returns false, with the time the quota resets. The message only counts once it is accepted, see count.
+func (q *QuotaTracker) allow(user string, now time.Time) (bool, time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.startPeriod(now)
+	if q.counts[user] >= q.limit {
+		return false, q.period.AddDate(0, 0, 1)
+	}
+	return true, time.Time{}
+}
+
+// count counts a message from user accepted at now. Rejected messages are not counted.
+func (q *QuotaTracker) count(user string, now time.Time) {
+	if q.limit <= 0 {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.startPeriod(now)
+	q.counts[user]++
+}
+
+// checkQuota returns why a user can't send another message if they used up their daily quota.
+func (s *ChatServer) checkQuota(user string) error {
+	if s.quota.limit <= 0 {
+		return nil
+	}
+
+	if ok, reset := s.quota.allow(user, s.clock.Now()); !ok {
+		return fmt.Errorf("you reached your daily quota of %d messages, it resets at %s", s.quota.limit, reset.Format("15:04 MST"))
+	}
+	return nil
+}
+
+// parseTimeOfDay parses a "15:04" time of day into an offset from midnight.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day in the 15:04 format", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+func TestDailyQuota(t *testing.T) {
+	clock := newFakeClock() // 12:00 UTC
+	config := testConfig()
+	config.DailyQuota = 2
+	config.QuotaResetAt = 18 * time.Hour
+	config.QuotaTimezone = "UTC"
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	send(t, alice, "one")
+	expectText(t, alice, "one")
+	send(t, alice, "two")
+	expectText(t, alice, "two")
+	send(t, alice, "three")
+	expectText(t, alice, "Message not sent: you reached your daily quota of 2 messages, it resets at 18:00 UTC.")
+
+	// The quota is per user
+	send(t, bob, "mine")
+	expectText(t, alice, "mine")
+
+	clock.Advance(6 * time.Hour)
+	send(t, alice, "four")
+	expectText(t, alice, "four")
+}
+
+func TestQuotaIgnoresRejectedMessages(t *testing.T) {
+	config := testConfig()
+	config.DailyQuota = 1
+	ts := startServer(t, config, newFakeClock())
+	alice := ts.join(t, "alice")
+
+	// A message rejected by a later check doesn't use up the quota
+	if err := alice.Send(&pb.ChatMessage{Text: "look", AttachmentUrl: "https://example.com/cat.png"}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	expectText(t, alice, "Message not sent: attachments from example.com are not allowed.")
+	send(t, alice, "fine")
+	expectText(t, alice, "fine")
+}
+
+func TestQuotaPeriodStart(t *testing.T) {
+	q := &QuotaTracker{resetAt: 3*time.Hour + 30*time.Minute, location: time.FixedZone("BRT", -3*60*60)}
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		// 02:00 local time, before the reset: the period started the day before
+		{time.Date(2024, time.March, 1, 5, 0, 0, 0, time.UTC), time.Date(2024, time.February, 29, 6, 30, 0, 0, time.UTC)},
+		{time.Date(2024, time.March, 1, 6, 30, 0, 0, time.UTC), time.Date(2024, time.March, 1, 6, 30, 0, 0, time.UTC)},
+		{time.Date(2024, time.March, 1, 23, 0, 0, 0, time.UTC), time.Date(2024, time.March, 1, 6, 30, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if got := q.periodStart(test.now); !got.Equal(test.want) {
+			t.Errorf("period of %v: got %v, want %v", test.now, got, test.want)
+		}
+	}
+}
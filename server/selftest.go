@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// selfTestUser is the user the self-test connects as. The name is reserved: only the self-test
+// of this server, holding its key, may use it, so it never displaces a real user.
+const selfTestUser = "selftest"
+
+// selfTestKeyMetadataKey carries the key proving a stream is the self-test of this server.
+const selfTestKeyMetadataKey = "x-chat-selftest-key"
+
+// selfTestTimeout bounds how long the self-test may take.
+const selfTestTimeout = 5 * time.Second
+
+// selfTest connects to the server at addr like a client would, sends a message and checks that it comes back.
+// It identifies both in the metadata and in the first message, so it works with either identity source,
+// and proves with key that it is the self-test, whose message isn't broadcast to the room.
+func selfTest(addr string, config Config, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+	defer cancel()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+	defer conn.Close()
+
+	// Announcements aren't part of the round trip, skip them
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		userMetadataKey, selfTestUser,
+		protocolVersionMetadataKey, strconv.FormatUint(uint64(config.MaxProtocolVersion), 10),
+		skipSystemMetadataKey, "true",
+		selfTestKeyMetadataKey, key)
+	stream, err := pb.NewChatServiceClient(conn).Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer stream.CloseSend()
+
+	if config.IdentitySource == identityFromMessage {
+		if err := stream.Send(&pb.ChatMessage{User: selfTestUser, ProtocolVersion: config.MaxProtocolVersion}); err != nil {
+			return fmt.Errorf("sending handshake: %w", err)
+		}
+	}
+	text := fmt.Sprintf("self-test %d", time.Now().UnixNano())
+	if err := stream.Send(&pb.ChatMessage{User: selfTestUser, Text: text}); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	// Other users may already be talking, wait for our own message
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("receiving message: %w", err)
+		}
+		if msg.User == selfTestUser && msg.Text == text {
+			return nil
+		}
+		// System broadcasts are skipped, so this is a notice for us alone
+		if msg.User == systemUser {
+			return fmt.Errorf("server answered: %s", msg.Text)
+		}
+	}
+}
+
+// isSelfTest reports whether a stream connecting as user is the self-test of this server.
+// Other clients claiming the reserved name are rejected.
+func (s *ChatServer) isSelfTest(ctx context.Context, user string) (bool, error) {
+	if user != selfTestUser {
+		return false, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if subtle.ConstantTimeCompare([]byte(firstValue(md, selfTestKeyMetadataKey)), []byte(s.selfTestKey)) != 1 {
+		return false, status.Errorf(codes.PermissionDenied, "the name %q is reserved", selfTestUser)
+	}
+	return true, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serveTCP serves a chat server with the given configuration on a local TCP port until the test ends,
+// returning its address and the key of its self-test.
+func serveTCP(t *testing.T, config Config) (addr, key string) {
+	t.Helper()
+
+	chatServer, err := NewChatServer(config, NopMetrics{}, realClock{})
+	if err != nil {
+		t.Fatalf("NewChatServer: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterChatServiceServer(grpcServer, chatServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	return lis.Addr().String(), chatServer.selfTestKey
+}
+
+func TestSelfTest(t *testing.T) {
+	for _, source := range []string{identityFromMessage, identityFromMetadata} {
+		config := testConfig()
+		config.IdentitySource = source
+		addr, key := serveTCP(t, config)
+		if err := selfTest(addr, config, key); err != nil {
+			t.Errorf("self-test with %s identities: %v", source, err)
+		}
+	}
+}
+
+func TestSelfTestReportsNotices(t *testing.T) {
+	config := testConfig()
+	config.MaxMessageLength = 5
+	addr, key := serveTCP(t, config)
+	err := selfTest(addr, config, key)
+	if err == nil || !strings.Contains(err.Error(), "server answered: Message not sent") {
+		t.Fatalf("self-test returned %v, want the notice of the server", err)
+	}
+}
+
+func TestSelfTestNameIsReserved(t *testing.T) {
+	config := testConfig()
+	addr, _ := serveTCP(t, config)
+	if err := selfTest(addr, config, "guess"); status.Code(errors.Unwrap(err)) != codes.PermissionDenied {
+		t.Fatalf("self-test with the wrong key returned %v, want PermissionDenied", err)
+	}
+
+	// Nor can a regular client claim the name
+	ts := startServer(t, config, realClock{})
+	expectCode(t, ts.open(t, selfTestUser), codes.PermissionDenied)
+}
+
+func TestSelfTestWhileOthersChat(t *testing.T) {
+	config := testConfig()
+	config.IdentitySource = identityFromMetadata
+	addr, key := serveTCP(t, config)
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, userMetadataKey, "alice", protocolVersionMetadataKey, strconv.Itoa(protocolVersion))
+	alice, err := pb.NewChatServiceClient(conn).Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect as alice: %v", err)
+	}
+	expectText(t, alice, "alice joined the room.")
+
+	// The self-test must pick its own message out of the chatter
+	done := make(chan struct{})
+	var chatting sync.WaitGroup
+	chatting.Add(1)
+	go func() {
+		defer chatting.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := alice.Send(&pb.ChatMessage{Text: "chatter"}); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	received := make(chan *pb.ChatMessage, 1024)
+	go func() {
+		defer close(received)
+		for {
+			msg, err := alice.Recv()
+			if err != nil {
+				return
+			}
+			received <- msg
+		}
+	}()
+
+	if err := selfTest(addr, config, key); err != nil {
+		t.Fatalf("self-test while alice chats: %v", err)
+	}
+	close(done)
+	chatting.Wait()
+
+	// Neither the self-test nor its message reached alice, who received everything before the marker
+	send(t, alice, "marker")
+	for msg := range received {
+		if msg.Text == "marker" {
+			return
+		}
+		if msg.User == selfTestUser || strings.Contains(msg.Text, selfTestUser) {
+			t.Fatalf("alice received %v from the self-test", msg)
+		}
+	}
+	t.Fatal("the stream of alice ended before the marker")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// What happens when a user connects while they already have a session.
+const (
+	sessionTerminateOld = "terminate-old" // The new session replaces the old one
+	sessionRejectNew    = "reject-new"    // The new session is refused
+)
+
+// displacedFlushTimeout bounds how long a replaced session may take to deliver its queue.
+const displacedFlushTimeout = 5 * time.Second
+
+// errAlreadyConnected rejects a second session under the reject-new policy.
+var errAlreadyConnected = status.Error(codes.AlreadyExists, "you are already connected from another session")
+
+// errLoggedInElsewhere closes a session replaced under the terminate-old policy.
+// Like on shutdown, the writer flushes its queue first so the notice gets through.
+var errLoggedInElsewhere = status.Error(codes.Aborted, "logged in elsewhere")
+
+// displace ends a session that was replaced by a newer one of the same user, letting them know why.
+func (s *ChatServer) displace(connection *Connection) {
+	log.Printf("Client '%s' logged in elsewhere. Closing the old session.", connection.user)
+	s.notify(connection, "You logged in elsewhere, this session is closed.")
+	connection.close(errLoggedInElsewhere)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestRejectNewSession(t *testing.T) {
+	config := testConfig()
+	config.SessionPolicy = sessionRejectNew
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	expectCode(t, ts.open(t, "alice"), codes.AlreadyExists)
+
+	// The first session is unaffected
+	send(t, alice, "still me")
+	expectText(t, alice, "still me")
+}
+
+func TestTerminateOldSession(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	oldAlice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	newAlice := ts.join(t, "alice")
+	expectText(t, oldAlice, "You logged in elsewhere, this session is closed.")
+	expectCode(t, oldAlice, codes.Aborted)
+
+	send(t, newAlice, "new session")
+	if msg := expectText(t, bob, "new session"); msg.User != "alice" {
+		t.Fatalf("message sent by %q, want alice", msg.User)
+	}
+}
+
+func TestClosedSessionStopsPublishing(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	bob := ts.join(t, "bob")
+
+	// The old session of alice was replaced, but its client is still sending
+	stream := newFakeStream(t, 1)
+	stream.recv <- &pb.ChatMessage{Text: "ghost"}
+	close(stream.recv)
+	connection := &Connection{stream: stream, user: "alice", done: make(chan struct{})}
+	connection.close(errLoggedInElsewhere)
+	ts.receiveMessages(connection)
+
+	send(t, bob, "marker")
+	for {
+		msg, err := bob.Recv()
+		if err != nil {
+			t.Fatalf("receiving: %v", err)
+		}
+		if msg.Text == "ghost" {
+			t.Fatal("the closed session published a message")
+		}
+		if msg.Text == "marker" {
+			return
+		}
+	}
+}
+
+func TestDisplacedFlushIsBounded(t *testing.T) {
+	clock := newFakeClock()
+	ts := startServer(t, testConfig(), clock)
+
+	// A client that stopped reading, with messages still queued
+	stream := newFakeStream(t, 0)
+	connection := &Connection{stream: stream, user: "alice", outbox: make(chan *pb.ChatMessage, 1), done: make(chan struct{})}
+	connection.outbox <- &pb.ChatMessage{Text: "never read"}
+	connection.close(errLoggedInElsewhere)
+
+	finished := make(chan error)
+	go func() { finished <- ts.finishMessages(connection) }()
+	clock.waitTimers(t, 1)
+	clock.Advance(displacedFlushTimeout)
+	select {
+	case err := <-finished:
+		if err != errLoggedInElsewhere {
+			t.Fatalf("finishMessages returned %v, want %v", err, errLoggedInElsewhere)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("the flush of a client that doesn't read never ended")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errShutdown closes the connections when the server stops.
+// Writers flush their queue before returning it to the client.
+var errShutdown = status.Error(codes.Unavailable, "server is shutting down")
+
+// Shutdown stops accepting clients and closes every connection once the messages
+// already accepted have been broadcast, or when ctx expires. Each writer flushes its
+// send queue before its stream ends; use grpc.Server.GracefulStop to wait for them.
+func (s *ChatServer) Shutdown(ctx context.Context) {
+	// 1. Refuse new clients from now on
+	s.mutex.Lock()
+	s.shuttingDown = true
+	s.mutex.Unlock()
+
+	// 2. Let everyone know and wait for the sequencer to catch up
+	shutdownMsg := &pb.ChatMessage{
+		User:      systemUser,
+		Type:      pb.MessageType_SYSTEM,
+		Text:      "Server is shutting down.",
+		Timestamp: timestamppb.New(s.clock.Now()),
+	}
+	s.publish(shutdownMsg)
+	s.waitSequencer(ctx)
+
+	// 3. Close every connection, their writers flush what is still queued
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, connection := range s.connections {
+		connection.close(errShutdown)
+	}
+}
+
+// waitSequencer waits until every message handed to the sequencer has been broadcast, or ctx expires.
+func (s *ChatServer) waitSequencer(ctx context.Context) {
+	if s.sequence == nil {
+		return
+	}
+
+	for s.pending.Load() > 0 {
+		timer := s.clock.NewTimer(10 * time.Millisecond)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// flushMessages sends whatever is left in a connection's queue, stopping at the first error or once timeout elapsed.
+// A client that stopped reading blocks the sends, so they run aside: giving up ends the stream, which fails the blocked send.
+func (s *ChatServer) flushMessages(connection *Connection, timeout time.Duration) {
+	flushed := make(chan struct{})
+	var abandoned atomic.Bool
+	go func() {
+		defer close(flushed)
+		for !abandoned.Load() {
+			select {
+			case msg := <-connection.outbox:
+				if err := connection.stream.Send(msg); err != nil {
+					return
+				}
+			default:
+				return
+			}
+		}
+	}()
+
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-flushed:
+	case <-timer.C():
+		abandoned.Store(true)
+		log.Printf("Gave up delivering the queue of %s after %v.", connection.user, timeout)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestShutdownDeliversQueuedMessages(t *testing.T) {
+	config := testConfig()
+	config.TotalOrder = true
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	// Bob's writer holds the messages back, as if the client was slow to read
+	ts.connection(t, "bob").paused.Store(true)
+	want := []string{"one", "two", "three"}
+	for _, text := range want {
+		send(t, alice, text)
+		expectText(t, alice, text)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ts.Shutdown(ctx)
+
+	// Everything accepted before the shutdown reaches bob before the stream ends
+	for _, text := range append(want, "Server is shutting down.") {
+		if msg := expectNext(t, bob); msg.Text != text {
+			t.Fatalf("bob received %v, want %q", msg, text)
+		}
+	}
+	expectCode(t, bob, codes.Unavailable)
+	expectCode(t, alice, codes.Unavailable)
+
+	// Nobody joins anymore
+	expectCode(t, ts.open(t, "carol"), codes.Unavailable)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// slowAction is the escalation stage reached by a client that keeps timing out.
+type slowAction int
+
+const (
+	slowWarn       slowAction = iota // Drop the message, log and count it
+	slowReduce                       // Drop the message and shrink the client's queue
+	slowDisconnect                   // Disconnect the client
+)
+
+// recordTimeout counts a send timeout for the connection and returns the stage reached.
+// Only the timeouts within the configured window count towards escalating.
+func (c *Connection) recordTimeout(now time.Time, config Config) slowAction {
+	c.slowMutex.Lock()
+	defer c.slowMutex.Unlock()
+
+	// Forget the timeouts that fell out of the window
+	recent := c.timeouts[:0]
+	for _, t := range c.timeouts {
+		if now.Sub(t) < config.SlowClientWindow {
+			recent = append(recent, t)
+		}
+	}
+	c.timeouts = append(recent, now)
+
+	switch n := len(c.timeouts); {
+	case n >= config.SlowClientDisconnectAfter:
+		return slowDisconnect
+	case config.SlowClientReduceAfter > 0 && n >= config.SlowClientReduceAfter:
+		return slowReduce
+	}
+	return slowWarn
+}
+
+// reduceQueue halves how many messages the connection may have queued, down to one, returning the new limit.
+func (c *Connection) reduceQueue() int32 {
+	for {
+		limit := c.queueLimit.Load()
+		reduced := max(limit/2, 1)
+		if c.queueLimit.CompareAndSwap(limit, reduced) {
+			return reduced
+		}
+	}
+}
+
+// restoreQueue gives a reduced connection its configured queue back once all its timeouts fell out of the window.
+// It reports whether the queue was restored.
+func (c *Connection) restoreQueue(now time.Time, config Config) bool {
+	if int(c.queueLimit.Load()) >= config.SendQueueSize {
+		return false
+	}
+
+	c.slowMutex.Lock()
+	defer c.slowMutex.Unlock()
+	if n := len(c.timeouts); n > 0 && now.Sub(c.timeouts[n-1]) < config.SlowClientWindow {
+		return false
+	}
+	c.timeouts = c.timeouts[:0]
+	c.queueLimit.Store(int32(config.SendQueueSize))
+	return true
+}
+
+// handleSendTimeout escalates when a message couldn't be queued for a client in time:
+// the first timeouts are only logged, then the client's queue is reduced, and eventually it is disconnected.
+func (s *ChatServer) handleSendTimeout(connection *Connection) {
+	s.metrics.IncCounter(metricSendTimeouts)
+
+	switch connection.recordTimeout(s.clock.Now(), s.config) {
+	case slowWarn:
+		log.Printf("Send to %s timed out. Dropping message.", connection.user)
+	case slowReduce:
+		limit := connection.reduceQueue()
+		log.Printf("Send to %s timed out again. Dropping message and reducing its queue to %d.", connection.user, limit)
+	case slowDisconnect:
+		log.Printf("Send to %s keeps timing out. Removing connection.", connection.user)
+		s.metrics.IncCounter(metricSlowDisconnects)
+		// We use a goroutine to avoid deadlock (removeConnection uses Lock and we are called under RLock)
+		go s.removeConnection(connection, status.Error(codes.ResourceExhausted, "too slow to keep up with the room"))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestSlowClientEscalation(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	connection := ts.connection(t, "alice")
+
+	// The first timeout is only a warning
+	ts.handleSendTimeout(connection)
+	if limit := connection.queueLimit.Load(); limit != int32(config.SendQueueSize) {
+		t.Fatalf("queue limit %d after a warning, want %d", limit, config.SendQueueSize)
+	}
+
+	// The second one halves the queue
+	clock.Advance(time.Second)
+	ts.handleSendTimeout(connection)
+	if limit := connection.queueLimit.Load(); limit != int32(config.SendQueueSize/2) {
+		t.Fatalf("queue limit %d after a reduction, want %d", limit, config.SendQueueSize/2)
+	}
+
+	// The third one disconnects
+	clock.Advance(time.Second)
+	ts.handleSendTimeout(connection)
+	expectCode(t, alice, codes.ResourceExhausted)
+}
+
+func TestSlowClientQueueRestored(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	connection := ts.connection(t, "alice")
+
+	ts.handleSendTimeout(connection)
+	ts.handleSendTimeout(connection)
+	if limit := connection.queueLimit.Load(); limit != int32(config.SendQueueSize/2) {
+		t.Fatalf("queue limit %d after a reduction, want %d", limit, config.SendQueueSize/2)
+	}
+
+	// Still within the window, the queue stays reduced
+	clock.Advance(config.SlowClientWindow / 2)
+	send(t, alice, "soon")
+	expectText(t, alice, "soon")
+	if limit := connection.queueLimit.Load(); limit != int32(config.SendQueueSize/2) {
+		t.Fatalf("queue limit %d within the window, want %d", limit, config.SendQueueSize/2)
+	}
+
+	// Once the timeouts left the window, the next delivery restores it
+	clock.Advance(config.SlowClientWindow)
+	send(t, alice, "later")
+	expectText(t, alice, "later")
+	eventually(t, func() bool { return connection.queueLimit.Load() == int32(config.SendQueueSize) })
+
+	// And the escalation starts over
+	ts.handleSendTimeout(connection)
+	if limit := connection.queueLimit.Load(); limit != int32(config.SendQueueSize) {
+		t.Fatalf("queue limit %d after a warning, want %d", limit, config.SendQueueSize)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SlowMode enforces a minimum interval between the messages of each user, like the slow mode of popular chat platforms.
+type SlowMode struct {
+	interval  time.Duration        // Minimum time between two messages of a user (0 disables)
+	mutex     sync.Mutex           // Mutex to protect lastSent and lastPrune
+	lastSent  map[string]time.Time // When each user last got a message through
+	lastPrune time.Time            // When lastSent was last cleared of users who can send again
+}
+
+// NewSlowMode creates a slow mode with the interval from the configuration.
+func NewSlowMode(config Config) *SlowMode {
+	return &SlowMode{
+		interval: config.SlowMode,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// wait returns how long user must still wait before sending a message at now, or zero if they may.
+// The message only counts once it is accepted, see record.
+func (m *SlowMode) wait(user string, now time.Time) time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Users who can send again don't need to be remembered
+	if now.Sub(m.lastPrune) >= m.interval {
+		for u, sent := range m.lastSent {
+			if now.Sub(sent) >= m.interval {
+				delete(m.lastSent, u)
+			}
+		}
+		m.lastPrune = now
+	}
+
+	if sent, ok := m.lastSent[user]; ok {
+		if remaining := m.interval - now.Sub(sent); remaining > 0 {
+			return remaining
+		}
+	}
+	return 0
+}
+
+// record starts the interval of user from a message accepted at now.
+// Messages rejected for any reason are not recorded, so they don't restart the wait.
+func (m *SlowMode) record(user string, now time.Time) {
+	if m.interval <= 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastSent[user] = now
+}
+
+// checkSlowMode returns why a user can't send another message yet if slow mode is on.
+func (s *ChatServer) checkSlowMode(user string) error {
+	if s.slowMode.interval <= 0 {
+		return nil
+	}
+
+	if remaining := s.slowMode.wait(user, s.clock.Now()); remaining > 0 {
+		// Round up, waiting "0s" would be confusing
+		seconds := (remaining + time.Second - 1) / time.Second
+		return fmt.Errorf("slow mode is on, wait %ds before sending another message", seconds)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowMode(t *testing.T) {
+	clock := newFakeClock()
+	config := testConfig()
+	config.SlowMode = 10 * time.Second
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+	bob := ts.join(t, "bob")
+
+	send(t, alice, "first")
+	expectText(t, alice, "first")
+	clock.Advance(500 * time.Millisecond)
+	send(t, alice, "too soon")
+	expectText(t, alice, "Message not sent: slow mode is on, wait 10s before sending another message.")
+
+	// Every user has their own interval
+	send(t, bob, "mine")
+	expectText(t, alice, "mine")
+
+	// A rejected message doesn't restart the interval
+	clock.Advance(9 * time.Second)
+	send(t, alice, "still too soon")
+	expectText(t, alice, "Message not sent: slow mode is on, wait 1s before sending another message.")
+	clock.Advance(500 * time.Millisecond)
+	send(t, alice, "second")
+	expectText(t, alice, "second")
+}
+
+func TestSlowModeForgetsIdleUsers(t *testing.T) {
+	m := NewSlowMode(Config{SlowMode: time.Second})
+	now := time.Now()
+	m.wait("alice", now)
+	m.record("alice", now)
+	m.record("bob", now.Add(500*time.Millisecond))
+	if m.wait("carol", now.Add(1200*time.Millisecond)) != 0 {
+		t.Fatal("carol had to wait for their first message")
+	}
+	if _, kept := m.lastSent["alice"]; kept {
+		t.Error("alice is still remembered once they may send again")
+	}
+	if _, kept := m.lastSent["bob"]; !kept {
+		t.Error("bob is forgotten while they must still wait")
+	}
+}
+
+func TestSlowModeIgnoresRejectedMessages(t *testing.T) {
+	clock := newFakeClock() // 12:00 UTC
+	config := testConfig()
+	config.SlowMode = 10 * time.Second
+	config.DailyQuota = 1
+	config.QuotaResetAt = 12*time.Hour + time.Minute
+	config.QuotaTimezone = "UTC"
+	ts := startServer(t, config, clock)
+	alice := ts.join(t, "alice")
+
+	send(t, alice, "first")
+	expectText(t, alice, "first")
+	clock.Advance(55 * time.Second)
+	send(t, alice, "over quota")
+	expectText(t, alice, "Message not sent: you reached your daily quota of 1 messages, it resets at 12:01 UTC.")
+
+	// The quota resets 5s later: the rejected message must not have restarted the slow mode wait
+	clock.Advance(5 * time.Second)
+	send(t, alice, "next day")
+	expectText(t, alice, "next day")
+}
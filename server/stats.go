@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RoomStats keeps the activity counters of the chat room.
+// It has its own Mutex so that recording a message doesn't contend with the connections map.
+type RoomStats struct {
+	mutex        sync.Mutex // Mutex to protect the counters
+	messageCount uint64     // Total chat messages received since startup
+	lastActivity time.Time  // When the last chat message was received
+	buckets      [60]uint64 // Messages received per second over the last minute
+	bucketTimes  [60]int64  // Unix second each bucket refers to
+}
+
+// record counts a chat message received at the given time.
+func (r *RoomStats) record(now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.messageCount++
+	r.lastActivity = now
+
+	// Each bucket holds one second; reset it if it still refers to an older minute
+	second := now.Unix()
+	i := second % int64(len(r.buckets))
+	if r.bucketTimes[i] != second {
+		r.bucketTimes[i] = second
+		r.buckets[i] = 0
+	}
+	r.buckets[i]++
+}
+
+// snapshot returns the counters as seen at the given time.
+func (r *RoomStats) snapshot(now time.Time) (count, perMinute uint64, lastActivity time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Only buckets written within the last minute contribute to the rate
+	oldest := now.Unix() - int64(len(r.buckets))
+	for i, n := range r.buckets {
+		if r.bucketTimes[i] > oldest {
+			perMinute += n
+		}
+	}
+	return r.messageCount, perMinute, r.lastActivity
+}
+
+// GetRoomStats returns the current activity statistics of the chat room.
+func (s *ChatServer) GetRoomStats(ctx context.Context, req *pb.RoomStatsRequest) (*pb.RoomStats, error) {
+	// Hold the read lock so the active users and counters describe the same moment
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count, perMinute, lastActivity := s.stats.snapshot(s.clock.Now())
+	stats := &pb.RoomStats{
+		MessageCount:      count,
+		ActiveUsers:       uint32(len(s.connections)),
+		MessagesPerMinute: perMinute,
+		Shedding:          s.shedder.Shedding(),
+		ProtectiveMode:    s.sendMonitor.Protective(),
+	}
+	if !lastActivity.IsZero() {
+		stats.LastActivity = timestamppb.New(lastActivity)
+	}
+	return stats, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+func TestGetRoomStats(t *testing.T) {
+	clock := newFakeClock()
+	ts := startServer(t, testConfig(), clock)
+	alice := ts.join(t, "alice")
+	ts.join(t, "bob")
+
+	stats, err := ts.client.GetRoomStats(context.Background(), &pb.RoomStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetRoomStats: %v", err)
+	}
+	if stats.ActiveUsers != 2 || stats.MessageCount != 0 || stats.LastActivity != nil {
+		t.Fatalf("stats of a quiet room: %v", stats)
+	}
+
+	// Announcements aren't activity, chat messages are
+	for _, text := range []string{"one", "two", "three"} {
+		send(t, alice, text)
+		expectText(t, alice, text)
+	}
+	stats, err = ts.client.GetRoomStats(context.Background(), &pb.RoomStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetRoomStats: %v", err)
+	}
+	if stats.MessageCount != 3 || stats.MessagesPerMinute != 3 || !stats.LastActivity.AsTime().Equal(clock.Now()) {
+		t.Fatalf("stats after 3 messages: %v", stats)
+	}
+
+	// A minute later, the rate is back to zero but the total stays
+	clock.Advance(time.Minute + time.Second)
+	stats, err = ts.client.GetRoomStats(context.Background(), &pb.RoomStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetRoomStats: %v", err)
+	}
+	if stats.MessageCount != 3 || stats.MessagesPerMinute != 0 {
+		t.Fatalf("stats a minute later: %v", stats)
+	}
+}
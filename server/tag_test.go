@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+)
+
+// writeRules writes a tag rules file in a directory removed when the test ends, returning its path.
+func writeRules(t *testing.T, rules string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatalf("writing rules: %v", err)
+	}
+	return path
+}
+
+func TestTagMessages(t *testing.T) {
+	config := testConfig()
+	config.TagRules = writeRules(t, `[
+		{"pattern": "\\?\\s*$", "tags": ["question"]},
+		{"pattern": "https?://", "tags": ["link"]},
+		{"pattern": "@\\w+", "tags": ["mention"]},
+		{"pattern": "https?://\\S+\\.png", "tags": ["link", "image"]}
+	]`)
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	// Only the server tags messages
+	text := "@bob did you see https://example.com/cat.png ?"
+	if err := alice.Send(&pb.ChatMessage{Text: text, Tags: []string{"urgent"}}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	msg := expectText(t, alice, text)
+	if want := []string{"question", "link", "mention", "image"}; !slices.Equal(msg.Tags, want) {
+		t.Errorf("got tags %q, want %q", msg.Tags, want)
+	}
+
+	if err := alice.Send(&pb.ChatMessage{Text: "plain", Tags: []string{"urgent"}}); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	if msg := expectText(t, alice, "plain"); len(msg.Tags) != 0 {
+		t.Errorf("got tags %q for an untagged message", msg.Tags)
+	}
+}
+
+func TestTaggerReload(t *testing.T) {
+	config := testConfig()
+	config.TagRules = writeRules(t, `[{"pattern": "\\?$", "tags": ["question"]}]`)
+	tagger, err := NewTagger(config)
+	if err != nil {
+		t.Fatalf("NewTagger: %v", err)
+	}
+
+	// A broken file keeps the previous rules
+	for _, rules := range []string{`[{"pattern": "(", "tags": ["broken"]}]`, `not json`} {
+		if err := os.WriteFile(config.TagRules, []byte(rules), 0o600); err != nil {
+			t.Fatalf("writing rules: %v", err)
+		}
+		if err := tagger.load(); err == nil {
+			t.Errorf("loading %s succeeded", rules)
+		}
+		if tags := tagger.tag("why?"); !slices.Equal(tags, []string{"question"}) {
+			t.Errorf("after loading %s: got tags %q, want the previous rules", rules, tags)
+		}
+	}
+
+	if err := os.WriteFile(config.TagRules, []byte(`[{"pattern": "!$", "tags": ["exclamation"]}]`), 0o600); err != nil {
+		t.Fatalf("writing rules: %v", err)
+	}
+	if err := tagger.load(); err != nil {
+		t.Fatalf("loading valid rules: %v", err)
+	}
+	if tags := tagger.tag("why?"); len(tags) != 0 {
+		t.Errorf("got tags %q from the replaced rules", tags)
+	}
+	if tags := tagger.tag("wow!"); !slices.Equal(tags, []string{"exclamation"}) {
+		t.Errorf("got tags %q, want the new rules", tags)
+	}
+}
+
+func TestInvalidTagRules(t *testing.T) {
+	config := testConfig()
+	config.TagRules = writeRules(t, `[{"pattern": "[a-", "tags": ["broken"]}]`)
+	if _, err := NewChatServer(config, NopMetrics{}, realClock{}); err == nil {
+		t.Fatal("NewChatServer accepted invalid tag rules")
+	}
+	config.TagRules = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := NewChatServer(config, NopMetrics{}, realClock{}); err == nil {
+		t.Fatal("NewChatServer accepted a missing tag rules file")
+	}
+}
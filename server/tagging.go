@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"sync/atomic"
+	"syscall"
+)
+
+// tagRule adds tags to the messages whose text matches a pattern.
+type tagRule struct {
+	Pattern string   `json:"pattern"` // Regular expression, in Go syntax
+	Tags    []string `json:"tags"`    // Tags added on a match
+	regexp  *regexp.Regexp
+}
+
+// Tagger categorizes messages with the rules of a JSON file, such as
+//
+//	[{"pattern": "\\?\\s*$", "tags": ["question"]}, {"pattern": "https?://", "tags": ["link"]}]
+//
+// Rules are applied in order and every matching rule adds its tags. The file is read again on SIGHUP.
+type Tagger struct {
+	path  string                    // File the rules are read from (empty disables)
+	rules atomic.Pointer[[]tagRule] // Rules currently in use, swapped on reload
+}
+
+// NewTagger creates a tagger with the rules file from the configuration, loading it right away.
+func NewTagger(config Config) (*Tagger, error) {
+	t := &Tagger{path: config.TagRules}
+	if t.path == "" {
+		return t, nil
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// load reads the rules file, only replacing the rules in use if every rule is valid.
+func (t *Tagger) load() error {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+	var rules []tagRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("parsing %s: %w", t.path, err)
+	}
+	for i := range rules {
+		if rules[i].regexp, err = regexp.Compile(rules[i].Pattern); err != nil {
+			return fmt.Errorf("rule %d of %s: %w", i+1, t.path, err)
+		}
+	}
+	t.rules.Store(&rules)
+	return nil
+}
+
+// reloadOnHangup reads the rules file again whenever the process receives SIGHUP.
+// A file that fails to load is reported and the previous rules are kept.
+func (t *Tagger) reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := t.load(); err != nil {
+			log.Printf("Error reloading tag rules, keeping the previous ones: %v", err)
+			continue
+		}
+		log.Printf("Tag rules reloaded from %s.", t.path)
+	}
+}
+
+// tag returns the tags of a text, in rule order and without duplicates.
+func (t *Tagger) tag(text string) []string {
+	rules := t.rules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	var tags []string
+	for _, rule := range *rules {
+		if !rule.regexp.MatchString(text) {
+			continue
+		}
+		for _, tag := range rule.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
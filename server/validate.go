@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Units the length of a message can be counted in.
+const (
+	lengthInBytes     = "byte"
+	lengthInRunes     = "rune"     // Unicode code points
+	lengthInGraphemes = "grapheme" // User-perceived characters, e.g. a family emoji counts as one
+)
+
+// What happens to messages containing control characters.
+const (
+	controlCharsOff    = "off"    // The message is broadcast as is
+	controlCharsStrip  = "strip"  // The characters are removed and the message is broadcast
+	controlCharsReject = "reject" // The message is not broadcast
+)
+
+// disallowedControl reports whether a rune is a control character that may break terminal clients,
+// such as NUL or the escape starting an ANSI sequence. Newlines and tabs are legitimate whitespace.
+func disallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t'
+}
+
+// checkControlChars applies the control character policy to a text, returning the text to broadcast
+// or why it can't be sent.
+func (s *ChatServer) checkControlChars(text string) (string, error) {
+	if s.config.ControlChars == controlCharsOff || strings.IndexFunc(text, disallowedControl) < 0 {
+		return text, nil
+	}
+	if s.config.ControlChars == controlCharsReject {
+		return "", errors.New("your message contains control characters")
+	}
+	return strings.Map(func(r rune) rune {
+		if disallowedControl(r) {
+			return -1
+		}
+		return r
+	}, text), nil
+}
+
+// messageLength measures a text in the given unit.
+func messageLength(text, unit string) int {
+	switch unit {
+	case lengthInBytes:
+		return len(text)
+	case lengthInGraphemes:
+		return uniseg.GraphemeClusterCount(text)
+	default:
+		return utf8.RuneCountInString(text)
+	}
+}
+
+// checkLength returns why a text can't be sent if it is over the configured length limit.
+func (s *ChatServer) checkLength(text string) error {
+	limit := s.config.MaxMessageLength
+	if limit <= 0 {
+		return nil
+	}
+
+	if length := messageLength(text, s.config.LengthUnit); length > limit {
+		unit := "characters"
+		if s.config.LengthUnit == lengthInBytes {
+			unit = "bytes"
+		}
+		return fmt.Errorf("your message is %d %s long, the limit is %d", length, unit, limit)
+	}
+	return nil
+}
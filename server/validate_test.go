@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestCheckControlChars(t *testing.T) {
+	const text = "hi\x00 there\x1b[2J\tand\nbye"
+	tests := []struct {
+		policy string
+		want   string
+		reject bool
+	}{
+		{controlCharsOff, text, false},
+		{controlCharsStrip, "hi there[2J\tand\nbye", false},
+		{controlCharsReject, "", true},
+	}
+	for _, test := range tests {
+		config := testConfig()
+		config.ControlChars = test.policy
+		s := &ChatServer{config: config}
+
+		got, err := s.checkControlChars(text)
+		if (err != nil) != test.reject {
+			t.Errorf("%s: error %v, want rejection %v", test.policy, err, test.reject)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.policy, got, test.want)
+		}
+		// Newlines and tabs are always fine
+		if got, err := s.checkControlChars("a\tb\nc"); err != nil || got != "a\tb\nc" {
+			t.Errorf("%s: got %q (%v) for plain whitespace", test.policy, got, err)
+		}
+	}
+}
+
+func TestRejectControlChars(t *testing.T) {
+	config := testConfig()
+	config.ControlChars = controlCharsReject
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	send(t, alice, "\x1b[31mred")
+	expectText(t, alice, "Message not sent: your message contains control characters.")
+}
+
+func TestSafeModeTurnsControlCharsOff(t *testing.T) {
+	config := testConfig()
+	config.ControlChars = controlCharsReject
+	config.applySafeMode()
+	if config.ControlChars != controlCharsOff {
+		t.Fatalf("safe mode left -control-chars %q", config.ControlChars)
+	}
+}
+
+func TestMessageLength(t *testing.T) {
+	const family = "👨‍👩‍👧" // Three emoji joined by zero width joiners
+	tests := []struct {
+		text string
+		unit string
+		want int
+	}{
+		{"hello", lengthInBytes, 5},
+		{"olá", lengthInBytes, 4},
+		{"olá", lengthInRunes, 3},
+		{"olá", lengthInGraphemes, 3},
+		{family, lengthInBytes, 18},
+		{family, lengthInRunes, 5},
+		{family, lengthInGraphemes, 1},
+	}
+	for _, test := range tests {
+		if got := messageLength(test.text, test.unit); got != test.want {
+			t.Errorf("length of %q in %ss: got %d, want %d", test.text, test.unit, got, test.want)
+		}
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	config := testConfig()
+	config.MaxMessageLength = 3
+	config.LengthUnit = lengthInGraphemes
+	ts := startServer(t, config, realClock{})
+	alice := ts.join(t, "alice")
+
+	// Three families are three characters, however many bytes they take
+	send(t, alice, "👨‍👩‍👧👨‍👩‍👧👨‍👩‍👧")
+	expectText(t, alice, "👨‍👩‍👧👨‍👩‍👧👨‍👩‍👧")
+	send(t, alice, "four")
+	expectText(t, alice, "Message not sent: your message is 4 characters long, the limit is 3.")
+
+	config.LengthUnit = lengthInBytes
+	s := &ChatServer{config: config}
+	if err := s.checkLength("olá"); err == nil || err.Error() != "your message is 4 bytes long, the limit is 3" {
+		t.Fatalf("checking %q in bytes: %v", "olá", err)
+	}
+	config.MaxMessageLength = 0
+	s = &ChatServer{config: config}
+	if err := s.checkLength("no limit at all"); err != nil {
+		t.Fatalf("checking without a limit: %v", err)
+	}
+}
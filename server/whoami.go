@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WhoAmI describes the caller's chat connection as the server sees it, to help client developers check their handshake.
+// The caller identifies with the same metadata as in Connect, whatever the identity source the server uses,
+// and the session token of its stream, since the connection details are only for the client holding it.
+func (s *ChatServer) WhoAmI(ctx context.Context, req *pb.WhoAmIRequest) (*pb.ConnectionInfo, error) {
+	connection, err := s.callerSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The compressors are those the client advertised when opening its chat stream
+	compressors, _ := grpc.ClientSupportedCompressors(connection.stream.Context())
+	return &pb.ConnectionInfo{
+		User:                connection.user,
+		ConnectionId:        connection.id,
+		ProtocolVersion:     connection.version,
+		JoinedAt:            timestamppb.New(connection.joinedAt),
+		AcceptedCompressors: compressors,
+		SkipSystemMessages:  connection.skipSystem,
+		QueueLimit:          uint32(connection.queueLimit.Load()),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/artursilveiradev/grpc-chat/server/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestWhoAmI(t *testing.T) {
+	config := testConfig()
+	config.SendQueueSize = 8
+	ts := startServer(t, config, newFakeClock())
+	alice := ts.open(t, "alice", skipSystemMetadataKey, "true")
+	connection := ts.connection(t, "alice")
+	ctx := sessionContext(t, "alice", alice)
+
+	info, err := ts.client.WhoAmI(ctx, &pb.WhoAmIRequest{})
+	if err != nil {
+		t.Fatalf("WhoAmI: %v", err)
+	}
+	compressors, _ := grpc.ClientSupportedCompressors(connection.stream.Context())
+	if info.User != "alice" || info.ConnectionId != connection.id || info.ProtocolVersion != protocolVersion ||
+		!info.JoinedAt.AsTime().Equal(connection.joinedAt) || !slices.Equal(info.AcceptedCompressors, compressors) ||
+		!info.SkipSystemMessages || info.QueueLimit != 8 {
+		t.Fatalf("WhoAmI returned %v for connection %d joined at %v", info, connection.id, connection.joinedAt)
+	}
+}
+
+func TestWhoAmIRequiresSessionToken(t *testing.T) {
+	ts := startServer(t, testConfig(), realClock{})
+	ts.join(t, "alice")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"without token", metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, "alice"), codes.InvalidArgument},
+		{"with a made-up token", metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, "alice", sessionTokenMetadataKey, "guess"), codes.PermissionDenied},
+		{"for a user who isn't connected", metadata.AppendToOutgoingContext(context.Background(), userMetadataKey, "bob", sessionTokenMetadataKey, "guess"), codes.NotFound},
+	}
+	for _, test := range tests {
+		if _, err := ts.client.WhoAmI(test.ctx, &pb.WhoAmIRequest{}); status.Code(err) != test.code {
+			t.Errorf("WhoAmI %s returned %v, want %v", test.name, err, test.code)
+		}
+	}
+}